@@ -0,0 +1,166 @@
+package go_bk_tree
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// AlphabetMetric selects the distance used between the PackedSequences of an Alphabet
+type AlphabetMetric int
+
+const (
+	// EditDistance is the Levenshtein distance (unit costs)
+	EditDistance AlphabetMetric = iota
+	// HammingDistance counts the positions holding different symbols, plus the length difference
+	HammingDistance
+)
+
+// Alphabet packs sequences over a small set of byte symbols (e.g. "ACGT" for DNA, at 2 bits
+// per symbol) into PackedSequences, a built-in MetricTensor for k-mers and similar data
+type Alphabet struct {
+	symbols string
+	metric  AlphabetMetric
+	bits    uint // per symbol: 1, 2, 4 or 8, so that a symbol never straddles two bytes
+	codes   [256]int16
+}
+
+// NewAlphabet creates an alphabet of at most 256 distinct symbols
+func NewAlphabet(symbols string, metric AlphabetMetric) (*Alphabet, error) {
+	if len(symbols) == 0 || len(symbols) > 256 {
+		return nil, fmt.Errorf("go_bk_tree: alphabet must have between 1 and 256 symbols, got %d", len(symbols))
+	}
+	a := &Alphabet{symbols: symbols, metric: metric, bits: 1}
+	for a.bits < 8 && 1<<a.bits < len(symbols) {
+		a.bits *= 2
+	}
+	for i := range a.codes {
+		a.codes[i] = -1
+	}
+	for i := 0; i < len(symbols); i++ {
+		if a.codes[symbols[i]] >= 0 {
+			return nil, fmt.Errorf("go_bk_tree: duplicate alphabet symbol %q", symbols[i])
+		}
+		a.codes[symbols[i]] = int16(i)
+	}
+	return a, nil
+}
+
+// Encode packs s, which must only contain symbols of the alphabet
+func (a *Alphabet) Encode(s string) (PackedSequence, error) {
+	perByte := 8 / a.bits
+	packed := make([]byte, (uint(len(s))+perByte-1)/perByte)
+	for i := 0; i < len(s); i++ {
+		code := a.codes[s[i]]
+		if code < 0 {
+			return PackedSequence{}, fmt.Errorf("go_bk_tree: %q at position %d is not in the alphabet %q", s[i], i, a.symbols)
+		}
+		packed[uint(i)/perByte] |= byte(code) << (uint(i) % perByte * a.bits)
+	}
+	return PackedSequence{alphabet: a, packed: packed, length: len(s)}, nil
+}
+
+// PackedSequence is a sequence encoded by an Alphabet. Only sequences of the same
+// Alphabet can be compared.
+type PackedSequence struct {
+	alphabet *Alphabet
+	packed   []byte
+	length   int
+}
+
+func (p PackedSequence) Len() int {
+	return p.length
+}
+
+func (p PackedSequence) at(i int) byte {
+	perByte := 8 / p.alphabet.bits
+	mask := byte(1)<<p.alphabet.bits - 1
+	if p.alphabet.bits == 8 {
+		mask = 0xff
+	}
+	return p.packed[uint(i)/perByte] >> (uint(i) % perByte * p.alphabet.bits) & mask
+}
+
+func (p PackedSequence) DistanceFrom(other MetricTensor) Distance {
+	o := other.(PackedSequence)
+	if p.alphabet.metric == HammingDistance {
+		return p.hamming(o)
+	}
+	return p.edit(o)
+}
+
+func (p PackedSequence) hamming(o PackedSequence) Distance {
+	short, long := p, o
+	if short.length > long.length {
+		short, long = long, short
+	}
+	// the padding bits of the last byte are zero in both sequences, the
+	// symbols of the longer one past the end are counted separately
+	perByte := 8 / p.alphabet.bits
+	full := uint(short.length) / perByte
+	dist := 0
+	for i := uint(0); i < full; i++ {
+		dist += differingSymbols(short.packed[i]^long.packed[i], p.alphabet.bits)
+	}
+	for i := int(full * perByte); i < short.length; i++ {
+		if short.at(i) != long.at(i) {
+			dist++
+		}
+	}
+	return Distance(dist + long.length - short.length)
+}
+
+// differingSymbols counts the non-zero symbols of x
+func differingSymbols(x byte, symbolBits uint) int {
+	for shift := uint(1); shift < symbolBits; shift *= 2 {
+		x |= x >> shift
+	}
+	var low byte
+	switch symbolBits {
+	case 1:
+		low = 0xff
+	case 2:
+		low = 0x55
+	case 4:
+		low = 0x11
+	default:
+		low = 0x01
+	}
+	return bits.OnesCount8(x & low)
+}
+
+func (p PackedSequence) edit(o PackedSequence) Distance {
+	s, t := p.unpack(), o.unpack()
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			sub := prev[j-1]
+			if s[i-1] != t[j-1] {
+				sub++
+			}
+			cur[j] = min(sub, prev[j]+1, cur[j-1]+1)
+		}
+		prev, cur = cur, prev
+	}
+	return Distance(prev[len(t)])
+}
+
+func (p PackedSequence) unpack() []byte {
+	codes := make([]byte, p.length)
+	for i := range codes {
+		codes[i] = p.at(i)
+	}
+	return codes
+}
+
+func (p PackedSequence) ToString() string {
+	s := make([]byte, p.length)
+	for i := range s {
+		s[i] = p.alphabet.symbols[p.at(i)]
+	}
+	return string(s)
+}
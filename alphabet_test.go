@@ -0,0 +1,48 @@
+package go_bk_tree
+
+import "testing"
+
+func TestAlphabet(t *testing.T) {
+	if _, err := NewAlphabet("ACGA", EditDistance); err == nil {
+		t.Error("expected an error for a duplicate symbol")
+	}
+	edit, _ := NewAlphabet("ACGT", EditDistance)
+	hamming, _ := NewAlphabet("ACGT", HammingDistance)
+	if _, err := edit.Encode("ACGU"); err == nil {
+		t.Error("expected an error for a symbol outside the alphabet")
+	}
+	cases := []struct {
+		a, b           string
+		edit, hammingD Distance
+	}{
+		{"ACGTACGT", "ACGTACGT", 0, 0},
+		{"ACGTACGT", "ACGAACGT", 1, 1},
+		{"ACGTACGTA", "CGTACGTA", 1, 9},
+		{"ACGTA", "ACGTAGG", 2, 2},
+		{"", "ACG", 3, 3},
+	}
+	for _, c := range cases {
+		a, _ := edit.Encode(c.a)
+		b, _ := edit.Encode(c.b)
+		if d := a.DistanceFrom(b); d != c.edit {
+			t.Errorf("edit %s -> %s: expected: %d, got: %d", c.a, c.b, c.edit, d)
+		}
+		a, _ = hamming.Encode(c.a)
+		b, _ = hamming.Encode(c.b)
+		if d := a.DistanceFrom(b); d != c.hammingD {
+			t.Errorf("hamming %s -> %s: expected: %d, got: %d", c.a, c.b, c.hammingD, d)
+		}
+		if a.ToString() != c.a {
+			t.Errorf("expected: %s, got: %s", c.a, a.ToString())
+		}
+	}
+	tree := new(BKTree)
+	for _, kmer := range []string{"ACGTAC", "ACGTAA", "TTTTTT", "ACCTAC"} {
+		p, _ := edit.Encode(kmer)
+		tree.Add(p)
+	}
+	query, _ := edit.Encode("ACGTAC")
+	if results, _ := tree.Search(query, 1); len(results) != 3 {
+		t.Errorf("expected: %d, got: %d", 3, len(results))
+	}
+}
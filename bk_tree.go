@@ -4,7 +4,10 @@
 package go_bk_tree
 
 import (
+	"errors"
+	"fmt"
 	"runtime"
+	"sort"
 	"time"
 
 	"github.com/pquerna/ffjson/ffjson"
@@ -12,6 +15,26 @@ import (
 
 type Distance int
 
+// ErrNegativeDistance is returned by Add and SearchChecked when the metric yields a negative
+// (invalid) distance, negative distances cannot be bucketed and would corrupt the tree
+var ErrNegativeDistance = errors.New("go_bk_tree: negative distance")
+
+// ErrNilValue is returned by Add (and the decoders) for a nil MetricTensor, which has no distance
+var ErrNilValue = errors.New("go_bk_tree: nil value")
+
+// ErrEmptyTree is returned by the error-returning search variants when the tree has no value
+var ErrEmptyTree = errors.New("go_bk_tree: empty tree")
+
+// ErrTreeFull is returned by Add when the tree already holds MaxSize values and Overflow is RejectNew
+var ErrTreeFull = errors.New("go_bk_tree: tree is full")
+
+// ErrWrongRootDistance is returned by AddWithRootDistance when MetricCheck is set and the hint is wrong
+var ErrWrongRootDistance = errors.New("go_bk_tree: wrong root distance hint")
+
+// ErrNondeterministicMetric is returned by Add and SearchChecked when CheckDeterminism is
+// set and the metric gave two different distances for the same pair
+var ErrNondeterministicMetric = errors.New("go_bk_tree: metric is not deterministic")
+
 // MetricTensor is an interface of data that needs to be indexed
 //
 // Example:
@@ -30,12 +53,38 @@ type MetricTensor interface {
 type BkTreeNode struct {
 	MetricTensor
 	Children map[Distance]*BkTreeNode
+	// AddedAt is the insertion time of the node, only set when the tree has Timestamps enabled
+	AddedAt time.Time
+	// Seq increases with insertion order from 1, only set when the tree has Sequence
+	// enabled, see SearchSorted
+	Seq uint64
+	// Duplicates counts the values Add dropped as duplicates of this one, see SearchFaceted
+	Duplicates int
+	// frozen holds the copy of the subtree made by FreezeSubtree, which Search walks
+	// instead of Children, until the subtree changes
+	frozen *FrozenTree
 }
 
+// MarshalJSON encodes the node as [value, {bucket: child}]. A nil value without
+// children holds nothing and is left out (null for the root), one with children would
+// lose them and fails with ErrNilValue, see Repair.
 func (node *BkTreeNode) MarshalJSON() ([]byte, error) {
+	if node.MetricTensor == nil {
+		if len(node.Children) > 0 {
+			return nil, fmt.Errorf("%w: %w with %d children", ErrInvalidTree, ErrNilValue, len(node.Children))
+		}
+		return []byte("null"), nil
+	}
 	var array = make([]interface{}, 2)
 	array[0] = node.MetricTensor.ToString()
-	array[1] = node.Children
+	children := make(map[string]*BkTreeNode, len(node.Children))
+	for dist, child := range node.Children {
+		if child.MetricTensor == nil && len(child.Children) == 0 {
+			continue
+		}
+		children[FormatDistance(dist)] = child
+	}
+	array[1] = children
 	return ffjson.Marshal(array)
 }
 
@@ -46,6 +95,16 @@ func newbkTreeNode(v MetricTensor) *BkTreeNode {
 	}
 }
 
+// sortedBuckets returns the distances of the node's children in ascending order
+func (node *BkTreeNode) sortedBuckets() []Distance {
+	buckets := make([]Distance, 0, len(node.Children))
+	for dist := range node.Children {
+		buckets = append(buckets, dist)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return buckets
+}
+
 func (node *BkTreeNode) getSize() int {
 	if len(node.Children) == 0 {
 		return 1
@@ -60,67 +119,446 @@ func (node *BkTreeNode) getSize() int {
 type BKTree struct {
 	Size int
 	Root *BkTreeNode
+	// MetricCheck, when set, makes Add and Search verify the triangle inequality on the values
+	// they visit, it is meant for tests of custom metrics and disabled (nil) by default
+	MetricCheck *MetricCheck
+	// CheckDeterminism, when set, makes Add and SearchChecked compute every distance twice and
+	// fail with ErrNondeterministicMetric when they differ, since a metric changing its mind
+	// misplaces values. It doubles the cost of the metric and is meant for tests.
+	CheckDeterminism bool
+	// Traversal selects the order in which Search visits candidates, the set of results is the same
+	Traversal TraversalOrder
+	// Frontier, when set, replaces Traversal and MaxFrontier: each Search creates a frontier
+	// with it and visits the candidates in the order it picks, e.g. BestFirstFrontier
+	Frontier func() FrontierStrategy
+	// Epsilon is the distance at or below which Add and Find consider two values identical,
+	// e.g. for quantized float metrics where rounding noise makes equal values 1 apart.
+	// A value within Epsilon of a node it meets while descending is dropped as a duplicate,
+	// so buckets 1 to Epsilon are never filled by Add. Defaults to 0 (exact duplicates only).
+	Epsilon Distance
+	// Timestamps makes Add record the insertion time of each node in AddedAt, see EvictOlderThan
+	Timestamps bool
+	// Sequence makes Add number each new node in Seq, so that SearchSorted can break ties by
+	// insertion order. Seq is held by every node (8 bytes each) whether set or not, Sequence
+	// only costs incrementing a counter per Add.
+	Sequence bool
+	// Normalizer, when set, maps every value to a canonical key (e.g. lower-cased) before any
+	// distance is computed, so that equivalent inputs land on the same node. Values are stored
+	// as Normalized, which keeps the original for display. Add, Find and the searches normalize
+	// their query too, so Find (and thus RemoveNode) matches any input equivalent to the stored
+	// one. It must be set before the first Add and never changed afterwards.
+	Normalizer func(MetricTensor) MetricTensor
+	// MaxSize, when positive, caps the number of values: adding a new value to a full
+	// tree is handled according to Overflow. Duplicates are dropped as usual.
+	MaxSize int
+	// Overflow selects what Add does with a new value when the tree holds MaxSize values
+	Overflow OverflowPolicy
+	// LinearScanBelow makes Search compare val with every value, without the candidate
+	// queue, when the tree holds fewer values than it. The results are the same but the
+	// number of distance computations is then Size. Disabled (0) by default. On random
+	// Hamming64 hashes (BenchmarkBKTree_Search_Small*) the scan is ~20% faster up to 64
+	// values and on par at 256, a metric pruning more of the tree lowers the crossover.
+	LinearScanBelow int
+	// MaxFrontier, when positive, bounds the breadth-first candidate queue of Search:
+	// while it holds more than MaxFrontier nodes, candidates are taken from its end,
+	// i.e. the overflow is explored depth-first, so the queue grows past the bound by
+	// at most the fan-out along one path. The results are the same, in another order.
+	// With 64 in BenchmarkBKTree_Search_MaxFrontier a search allocates 13KB, not 3.5MB.
+	MaxFrontier int
+	// MaxResults, when positive, caps the number of values returned by the searches:
+	// Search, SearchWithHint, SearchExact, SearchFilter, SearchExhaustive, SearchChecked,
+	// SearchApprox, SearchMaxDepth, SearchDedup (counting groups), SearchWithNearestMiss,
+	// SearchWithProgress, SearchScored, SearchUnion, SearchRadiusFunc, SearchMultiRadius
+	// (counting the values of all bands), SearchEditString, StreamingSearch.Search,
+	// Matches and Stream collect values in traversal order and stop traversing at the
+	// cap, so they return the first matches found and not the closest ones; the ranked
+	// ones (SearchKNN, SearchKNNProgressive, SearchFaceted, SearchRerank, SearchSorted,
+	// SearchTargetCount) keep the best ones. Freeze copies it to the FrozenTree, whose
+	// Search stops at the cap too; AllPairsWithin and SearchHammingBatch ignore it.
+	MaxResults int
+	// ResultKey, when set, makes Search, SearchWithHint and SearchFaceted collapse the
+	// matches sharing the same key into the one closest to the query, kept at the position
+	// of the group's first match, SearchFaceted summing the Counts of the group. Values
+	// equivalent under Normalizer already share a node (the first one added, the others
+	// counted in Duplicates), so ResultKey is for a coarser form than the indexed one, e.g.
+	// the lower-cased word of a case-sensitive tree. MaxResults applies before collapsing.
+	ResultKey func(MetricTensor) string
+	// ChildrenCap sets how Add allocates the Children map of a new node: 0 allocates an
+	// empty map, a positive value presizes it for ChildrenCap children and a negative one
+	// leaves it nil until the first child, which saves the map of every leaf. Most nodes
+	// are leaves, so presizing costs more than it saves: on 20000 random Hamming64 values
+	// (BenchmarkBKTree_Add_ChildrenCap*) 16 allocates 4x more memory and builds 3x slower
+	// than 0, while the lazy allocation takes 17% less memory and builds ~25% faster.
+	ChildrenCap int
+	// Hooks are optional callbacks observing the operations on the tree, nil by default
+	Hooks *Hooks
+
+	// dirty and sizedRoot tell Len when Size may be stale, see MarkDirty
+	dirty     bool
+	sizedRoot *BkTreeNode
+	// version counts the mutations, see Version
+	version uint64
+	// seq is the Seq of the last node numbered by Add
+	seq uint64
 }
 
+// OverflowPolicy is what Add does when a tree with a MaxSize is full
+type OverflowPolicy int
+
+const (
+	// RejectNew leaves the tree untouched and fails with ErrTreeFull (the default)
+	RejectNew OverflowPolicy = iota
+	// EvictOldest removes the value with the oldest AddedAt, so it needs Timestamps.
+	// Finding it walks the whole tree and its subtree is reinserted, as with
+	// EvictOlderThan, so every insertion into a full tree costs O(n).
+	EvictOldest
+)
+
+// TraversalOrder is the order in which Search visits the candidate nodes
+type TraversalOrder int
+
+const (
+	// BreadthFirst visits the tree level by level using a queue (the default). The
+	// queue holds whole levels of candidates, which can grow large on wide trees.
+	BreadthFirst TraversalOrder = iota
+	// DepthFirst uses an explicit stack, so that at any time only the pending siblings
+	// along the current path are held. It keeps the frontier small for huge radii.
+	DepthFirst
+)
+
 func (tree *BKTree) ToJson() ([]byte, error) {
 	return ffjson.Marshal(tree.Root)
 }
 
 // Add a node to BK-Tree, the location of the new node
-// depends on how distance between different tensors are defined
-func (tree *BKTree) Add(val MetricTensor) {
-	node := newbkTreeNode(val)
+// depends on how distance between different tensors are defined.
+// The value is rejected with ErrNegativeDistance, leaving the tree
+// untouched, if the metric returns a negative distance on the way.
+func (tree *BKTree) Add(val MetricTensor) error {
+	if val == nil {
+		return ErrNilValue
+	}
+	return tree.observeAdd(val, tree.insert(tree.newNode(val), 0, false))
+}
+
+// AddWithRootDistance works like Add but trusts rootDist as the distance between the
+// root and val instead of computing it, for callers who already know it (e.g. from a
+// previous search). A wrong hint misplaces the value, so when MetricCheck is set the
+// hint is verified and a mismatch is rejected with ErrWrongRootDistance.
+func (tree *BKTree) AddWithRootDistance(val MetricTensor, rootDist Distance) error {
+	if val == nil {
+		return ErrNilValue
+	}
+	if tree.Root != nil && tree.Root.MetricTensor != nil && tree.MetricCheck != nil {
+		val = tree.normalize(val)
+		if dist := tree.Root.DistanceFrom(val); dist != rootDist {
+			return fmt.Errorf("%w: %d hinted for %s, actual %d", ErrWrongRootDistance, rootDist, val.ToString(), dist)
+		}
+	}
+	return tree.observeAdd(val, tree.insert(tree.newNode(val), rootDist, true))
+}
+
+// newNode creates a node holding val, stamped if the tree has Timestamps enabled and
+// numbered if it has Sequence enabled
+func (tree *BKTree) newNode(val MetricTensor) *BkTreeNode {
+	node := &BkTreeNode{MetricTensor: tree.normalize(val), Children: tree.newChildren()}
+	if tree.Timestamps {
+		node.AddedAt = time.Now()
+	}
+	if tree.Sequence {
+		tree.seq++
+		node.Seq = tree.seq
+	}
+	return node
+}
+
+// newChildren returns the Children map of a new node according to ChildrenCap
+func (tree *BKTree) newChildren() map[Distance]*BkTreeNode {
+	if tree.ChildrenCap < 0 {
+		return nil
+	}
+	return make(map[Distance]*BkTreeNode, tree.ChildrenCap)
+}
+
+// insert links a childless node into the tree, its metadata is kept as is
+func (tree *BKTree) insert(node *BkTreeNode, rootDist Distance, hinted bool) error {
+	val := node.MetricTensor
 	if tree.Root == nil {
 		tree.Size = 1
 		tree.Root = node
-		return
+		tree.sizedRoot = node
+		tree.version++
+		return nil
 	}
 	curNode := tree.Root
+	// covering is the node of the path holding a frozen copy, stale once node is linked
+	var covering *BkTreeNode
+	var visited []Match
+	if tree.MetricCheck != nil {
+		defer func() { tree.MetricCheck.check(val, visited) }()
+	}
 	for {
-		dist := curNode.DistanceFrom(val)
-		// If distance is zero which means two Metrics
-		// are exactly the same, return directly
-		if dist == 0 {
-			break
+		if covering == nil && curNode.frozen != nil {
+			covering = curNode
+		}
+		if curNode.MetricTensor == nil {
+			// a nil value (e.g. from a buggy loader) gives no distance to place val by
+			return fmt.Errorf("%w: %w on the path of %s", ErrInvalidTree, ErrNilValue, val.ToString())
+		}
+		var dist Distance
+		if hinted {
+			dist, hinted = rootDist, false
+		} else {
+			var err error
+			if dist, err = tree.distance(curNode, val); err != nil {
+				return err
+			}
+		}
+		if tree.MetricCheck != nil {
+			visited = append(visited, Match{curNode.MetricTensor, dist})
+		}
+		if dist < 0 {
+			return fmt.Errorf("%w: %d between %s and %s", ErrNegativeDistance, dist, curNode.ToString(), val.ToString())
+		}
+		// If distance is zero (or within Epsilon) which means two
+		// Metrics are exactly the same, return directly
+		if dist <= tree.Epsilon {
+			curNode.Duplicates += 1
+			tree.version++
+			return nil
 		}
 		target := curNode.Children[dist]
 		if target == nil {
+			if tree.MaxSize > 0 && tree.Size >= tree.MaxSize {
+				if tree.Overflow != EvictOldest {
+					return ErrTreeFull
+				}
+				// the path to the new node may change, so start over
+				tree.evictOldest()
+				return tree.insert(node, 0, false)
+			}
+			if curNode.Children == nil {
+				curNode.Children = make(map[Distance]*BkTreeNode)
+			}
 			curNode.Children[dist] = node
+			if covering != nil {
+				covering.frozen = nil
+			}
 			tree.Size += 1
-			break
+			tree.version++
+			return nil
 		}
 		curNode = target
 	}
 }
 
+// distance returns the distance between node and val, checking it when CheckDeterminism is set
+func (tree *BKTree) distance(node *BkTreeNode, val MetricTensor) (Distance, error) {
+	dist := node.DistanceFrom(val)
+	if tree.CheckDeterminism {
+		if again := node.DistanceFrom(val); again != dist {
+			return dist, fmt.Errorf("%w: %d then %d between %s and %s", ErrNondeterministicMetric, dist, again, node.ToString(), val.ToString())
+		}
+	}
+	return dist, nil
+}
+
+// CalculateSize resets Size to the actual number of nodes, see CountDistinct
 func (tree *BKTree) CalculateSize() {
-	tree.Size = tree.Root.getSize()
+	tree.Size = tree.CountDistinct()
+	tree.dirty = false
+	tree.sizedRoot = tree.Root
+}
+
+// Len returns the number of values. Size is kept up to date by the methods of the
+// tree, but not when nodes are linked or unlinked by hand: Len recomputes it once
+// (walking the whole tree) when Root was replaced since Size was last known to be
+// right, or after MarkDirty, and then caches it until the next such change.
+func (tree *BKTree) Len() int {
+	if tree.dirty || tree.sizedRoot != tree.Root {
+		tree.CalculateSize()
+	}
+	return tree.Size
+}
+
+// MarkDirty tells Len that Size may be stale, e.g. after editing Children directly,
+// and counts as a mutation for Version
+func (tree *BKTree) MarkDirty() {
+	tree.dirty = true
+	tree.version++
+}
+
+// Version returns a counter incremented by every mutation made through the methods of
+// the tree (including a duplicate counted by Add), so that comparing two versions
+// tells whether results obtained in between may be stale. Edits made by hand are only
+// counted by calling MarkDirty.
+func (tree *BKTree) Version() uint64 {
+	return tree.version
+}
+
+// CountDistinct walks the tree and returns the number of distinct values it holds
+// (one per node, duplicates are never stored), independently of the Size field
+func (tree *BKTree) CountDistinct() int {
+	if tree.Root == nil {
+		return 0
+	}
+	return tree.Root.getSize()
 }
 
 func (tree *BKTree) Search(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	return tree.search(val, radius, 5, 10)
+}
+
+// SearchWithHint works like Search but preallocates room for expectedResults
+// results (and candidates), which avoids repeated slice growth on large-radius queries
+func (tree *BKTree) SearchWithHint(val MetricTensor, radius Distance, expectedResults int) ([]MetricTensor, int) {
+	candCap := expectedResults
+	if candCap < 10 {
+		candCap = 10
+	}
+	if expectedResults < 0 {
+		expectedResults = 0
+	}
+	return tree.search(val, radius, expectedResults, candCap)
+}
+
+func (tree *BKTree) search(val MetricTensor, radius Distance, resultCap, candCap int) ([]MetricTensor, int) {
+	if tree.Hooks == nil || tree.Hooks.OnSearch == nil {
+		results, count := tree.searchNodes(val, radius, resultCap, candCap)
+		return tree.collapse(val, results), count
+	}
+	start := time.Now()
+	results, count := tree.searchNodes(val, radius, resultCap, candCap)
+	results = tree.collapse(val, results)
+	tree.Hooks.OnSearch(val, radius, SearchStats{Results: len(results), Visited: count, Duration: time.Since(start)})
+	return results, count
+}
+
+// collapse keeps the closest of the results sharing a ResultKey, in place
+func (tree *BKTree) collapse(val MetricTensor, results []MetricTensor) []MetricTensor {
+	if tree.ResultKey == nil || len(results) < 2 {
+		return results
+	}
+	val = tree.normalize(val)
+	groups := make(map[string]int, len(results))
+	dists := make([]Distance, 0, len(results))
+	collapsed := results[:0]
+	for _, r := range results {
+		dist := r.DistanceFrom(val)
+		k := tree.ResultKey(r)
+		if i, ok := groups[k]; ok {
+			if dist < dists[i] {
+				collapsed[i], dists[i] = r, dist
+			}
+			continue
+		}
+		groups[k] = len(collapsed)
+		collapsed = append(collapsed, r)
+		dists = append(dists, dist)
+	}
+	return collapsed
+}
+
+func (tree *BKTree) searchNodes(val MetricTensor, radius Distance, resultCap, candCap int) ([]MetricTensor, int) {
+	if val == nil || tree.Root == nil {
+		return make([]MetricTensor, 0, resultCap), 0
+	}
+	val = tree.normalize(val)
+	if tree.Size < tree.LinearScanBelow && tree.MetricCheck == nil {
+		return tree.Root.scan(val, radius, make([]MetricTensor, 0, resultCap), tree.MaxResults), tree.Size
+	}
+	if tree.Frontier != nil {
+		return tree.searchFrontier(val, radius, resultCap)
+	}
 	count := 0
-	candidates := make([]*BkTreeNode, 0, 10)
+	candidates := make([]*BkTreeNode, 0, candCap)
 	candidates = append(candidates, tree.Root)
-	results := make([]MetricTensor, 0, 5)
-	for {
-		cand := candidates[0]
-		candidates = candidates[1:]
+	results := make([]MetricTensor, 0, resultCap)
+	var visited []Match
+	if tree.MetricCheck != nil {
+		defer func() { tree.MetricCheck.check(val, visited) }()
+	}
+	for len(candidates) > 0 {
+		var cand *BkTreeNode
+		if tree.Traversal == DepthFirst || tree.MaxFrontier > 0 && len(candidates) > tree.MaxFrontier {
+			cand = candidates[len(candidates)-1]
+			candidates = candidates[:len(candidates)-1]
+		} else {
+			cand = candidates[0]
+			candidates = candidates[1:]
+		}
+		if cand.MetricTensor == nil {
+			// a nil value (e.g. from a buggy loader) gives no bound, explore every child
+			candidates = appendChildren(candidates, cand)
+			continue
+		}
 		dist := cand.DistanceFrom(val)
 		count += 1
+		if tree.MetricCheck != nil {
+			visited = append(visited, Match{cand.MetricTensor, dist})
+		}
 		if dist <= radius {
 			results = append(results, cand.MetricTensor)
+			if tree.capped(len(results)) {
+				break
+			}
 		}
-		low, high := dist-radius, dist+radius
+		if cand.frozen != nil {
+			var frozenCount int
+			results, frozenCount = cand.frozen.searchBelow(val, radius, dist, results, tree.MaxResults)
+			count += frozenCount
+			if tree.capped(len(results)) {
+				break
+			}
+			continue
+		}
+		low, high := searchWindow(dist, radius)
 		for dist, child := range cand.Children {
 			if dist >= low && dist <= high {
 				candidates = append(candidates, child)
 			}
 		}
-		if len(candidates) == 0 {
+	}
+	return results, count
+}
+
+// appendChildren appends every child of node to candidates
+func appendChildren(candidates []*BkTreeNode, node *BkTreeNode) []*BkTreeNode {
+	for _, child := range node.Children {
+		candidates = append(candidates, child)
+	}
+	return candidates
+}
+
+// scan appends the values of the subtree within radius of val to results, ignoring
+// pruning, until results holds limit values (if limit is positive)
+func (node *BkTreeNode) scan(val MetricTensor, radius Distance, results []MetricTensor, limit int) []MetricTensor {
+	if node.MetricTensor != nil && node.DistanceFrom(val) <= radius {
+		results = append(results, node.MetricTensor)
+	}
+	if node.frozen != nil {
+		for _, v := range node.frozen.values[1:] {
+			if limit > 0 && len(results) >= limit {
+				break
+			}
+			if v != nil && v.DistanceFrom(val) <= radius {
+				results = append(results, v)
+			}
+		}
+		return results
+	}
+	for _, child := range node.Children {
+		if limit > 0 && len(results) >= limit {
 			break
 		}
+		results = child.scan(val, radius, results, limit)
 	}
-	return results, count
+	return results
+}
+
+// capped reports whether n results reach MaxResults
+func (tree *BKTree) capped(n int) bool {
+	return tree.MaxResults > 0 && n >= tree.MaxResults
 }
 
 var numCPU = runtime.NumCPU()
@@ -140,7 +578,7 @@ LOOP:
 				if dist <= radius {
 					results = append(results, cand.MetricTensor)
 				}
-				low, high := dist-radius, dist+radius
+				low, high := searchWindow(dist, radius)
 				for dist, child := range cand.Children {
 					if dist >= low && dist <= high {
 						candsChan <- child
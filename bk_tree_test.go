@@ -4,9 +4,14 @@ Package go-bk-tree works like a charm
 package go_bk_tree
 
 import (
+	"errors"
 	"fmt"
 	l "github.com/texttheater/golang-levenshtein/levenshtein"
 	"math/rand"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -16,8 +21,12 @@ func (w Word) DistanceFrom(w2 MetricTensor) Distance {
 	return Distance(l.DistanceForStrings([]rune(string(w)), []rune(string(w2.(Word))), l.DefaultOptions))
 }
 
+func (w Word) ToString() string {
+	return string(w)
+}
+
 func createNewTreeFromWords(words []string) *BKTree {
-	tree := new(BKTree)
+	tree := &BKTree{CheckDeterminism: true}
 	for w := range words {
 		tree.Add(Word(words[w]))
 	}
@@ -27,14 +36,14 @@ func createNewTreeFromWords(words []string) *BKTree {
 func TestBKTree_Add(t *testing.T) {
 	wordsList := []string{"a", "ab", "abc", "d"}
 	tree := createNewTreeFromWords(wordsList)
-	if rootVal := string(tree.root.MetricTensor.(Word)); rootVal != "a" {
+	if rootVal := string(tree.Root.MetricTensor.(Word)); rootVal != "a" {
 		t.Errorf("expected: %s, got: %s", "a", rootVal)
 	}
-	level1Children := tree.root.Children
+	level1Children := tree.Root.Children
 	if len(level1Children) != 2 {
 		t.Errorf("expected: %d, got: %d", 2, len(level1Children))
 	}
-	level2Children := tree.root.Children[2].Children // 'd' should be child of 'abc'
+	level2Children := tree.Root.Children[2].Children // 'd' should be child of 'abc'
 	if len(level2Children) != 1 {
 		t.Errorf("expected: %d, got: %d", 1, len(level2Children))
 	}
@@ -48,11 +57,11 @@ func ExampleBKTree_Search() {
 
 	// fuzzy match
 	query := Word("sort")
-	results := tree.Search(query, 2)
+	results, _ := tree.Search(query, 2)
 	fmt.Println(results)
 	// exact match
 	query2 := Word("mole")
-	results2 := tree.Search(query2, 0)
+	results2, _ := tree.Search(query2, 0)
 	fmt.Println(results2)
 	// Output:
 	// [soft sorted]
@@ -80,6 +89,10 @@ func (n Number) DistanceFrom(other MetricTensor) Distance {
 	return Distance(hamming(uint64(n), uint64(other.(Number))))
 }
 
+func (n Number) ToString() string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
 func createNewTreeFromNumbers(nums []Number) *BKTree {
 	tree := new(BKTree)
 	for i := range nums {
@@ -150,3 +163,851 @@ func BenchmarkBKTree_Search_Radius32Match(b *testing.B) {
 		benchmarkTree.Search(randNum, 0)
 	}
 }
+
+func TestBKTree_SearchApprox(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 2)
+	exact, exactCount := tree.Search(hashes[0], 16)
+	unbounded, _ := tree.SearchApprox(hashes[0], 16, 0)
+	if !reflect.DeepEqual(sortedStrings(exact), sortedStrings(unbounded)) {
+		t.Errorf("expected: %v, got: %v", sortedStrings(exact), sortedStrings(unbounded))
+	}
+	approx, approxCount := tree.SearchApprox(hashes[0], 16, 2)
+	if approxCount > exactCount || len(approx) > len(exact) {
+		t.Errorf("expected approximate search to do less work, got %d visits vs %d", approxCount, exactCount)
+	}
+	again, _ := tree.SearchApprox(hashes[0], 16, 2)
+	if !reflect.DeepEqual(approx, again) {
+		t.Errorf("expected deterministic results, got: %v and %v", approx, again)
+	}
+}
+
+func TestBKTree_SearchWithHint(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 2)
+	expected, expectedCount := tree.Search(hashes[0], 16)
+	got, count := tree.SearchWithHint(hashes[0], 16, 1000)
+	if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || count != expectedCount {
+		t.Errorf("expected: %v, got: %v", sortedStrings(expected), sortedStrings(got))
+	}
+	if cap(got) < 1000 {
+		t.Errorf("expected capacity of at least %d, got: %d", 1000, cap(got))
+	}
+}
+
+func BenchmarkBKTree_Search_LargeRadius(b *testing.B) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(hashes[i%len(hashes)], 24)
+	}
+}
+
+func BenchmarkBKTree_SearchWithHint_LargeRadius(b *testing.B) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.SearchWithHint(hashes[i%len(hashes)], 24, 4096)
+	}
+}
+
+func TestBKTree_SearchExact(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 5)
+	for _, d := range []Distance{0, 10, 25, 32} {
+		results, _ := tree.SearchExact(hashes[0], d)
+		var expected []MetricTensor
+		for _, h := range hashes {
+			if h.DistanceFrom(hashes[0]) == d {
+				expected = append(expected, h)
+			}
+		}
+		if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(results)) {
+			t.Errorf("distance %d: expected: %v, got: %v", d, sortedStrings(expected), sortedStrings(results))
+		}
+	}
+}
+
+func TestBKTree_SearchDedup(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"sort", "Sort", "SORT", "sorts", "Sorted"})
+	results, _ := tree.SearchDedup(Word("Sort"), 4, func(v MetricTensor) string {
+		return strings.ToLower(v.ToString())
+	})
+	expected := []string{"Sort", "Sorted", "sorts"}
+	if got := sortedStrings(results); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+}
+
+// signedNumber returns the signed difference, a buggy metric
+type signedNumber int
+
+func (n signedNumber) DistanceFrom(other MetricTensor) Distance {
+	return Distance(int(n) - int(other.(signedNumber)))
+}
+
+func (n signedNumber) ToString() string {
+	return strconv.Itoa(int(n))
+}
+
+func TestBKTree_Add_NegativeDistance(t *testing.T) {
+	tree := new(BKTree)
+	for _, n := range []signedNumber{5, 3, 4} {
+		if err := tree.Add(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tree.Add(signedNumber(7)); !errors.Is(err, ErrNegativeDistance) {
+		t.Errorf("expected: %v, got: %v", ErrNegativeDistance, err)
+	}
+	if tree.Size != 3 || tree.Root.getSize() != 3 {
+		t.Errorf("expected the tree to be untouched, got size %d", tree.Size)
+	}
+}
+
+func TestBKTree_Search_DepthFirst(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 11)
+	expected, expectedCount := tree.Search(hashes[0], 20)
+	tree.Traversal = DepthFirst
+	got, count := tree.Search(hashes[0], 20)
+	if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || count != expectedCount {
+		t.Errorf("expected: %v, got: %v", sortedStrings(expected), sortedStrings(got))
+	}
+}
+
+func TestBKTree_Search_MaxFrontier(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 11)
+	expected, expectedCount := tree.Search(hashes[0], 20)
+	tree.MaxFrontier = 8
+	got, count := tree.Search(hashes[0], 20)
+	if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || count != expectedCount {
+		t.Errorf("expected: %v, got: %v", sortedStrings(expected), sortedStrings(got))
+	}
+}
+
+func benchmarkSearchTraversal(b *testing.B, order TraversalOrder, maxFrontier int) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	tree.Traversal = order
+	tree.MaxFrontier = maxFrontier
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(hashes[i%len(hashes)], 20)
+	}
+}
+
+func BenchmarkBKTree_Search_BreadthFirst(b *testing.B) {
+	benchmarkSearchTraversal(b, BreadthFirst, 0)
+}
+
+func BenchmarkBKTree_Search_MaxFrontier(b *testing.B) {
+	benchmarkSearchTraversal(b, BreadthFirst, 64)
+}
+
+func BenchmarkBKTree_Search_DepthFirst(b *testing.B) {
+	benchmarkSearchTraversal(b, DepthFirst, 0)
+}
+
+func benchmarkSearchFrontier(b *testing.B, frontier func() FrontierStrategy) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	tree.Frontier = frontier
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(hashes[i%len(hashes)], 20)
+	}
+}
+
+func BenchmarkBKTree_Search_FIFOFrontier(b *testing.B) {
+	benchmarkSearchFrontier(b, FIFOFrontier)
+}
+
+func BenchmarkBKTree_Search_LIFOFrontier(b *testing.B) {
+	benchmarkSearchFrontier(b, LIFOFrontier)
+}
+
+func BenchmarkBKTree_Search_BestFirstFrontier(b *testing.B) {
+	benchmarkSearchFrontier(b, BestFirstFrontier)
+}
+
+func TestBKTree_AnyCloserThan(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	v, dist, ok := tree.AnyCloserThan(Word("sort"), 3)
+	if !ok || dist >= 3 || v.DistanceFrom(Word("sort")) != dist {
+		t.Errorf("unexpected result: %v, %d, %v", v, dist, ok)
+	}
+	if _, _, ok := tree.AnyCloserThan(Word("sort"), 2); ok {
+		t.Error("expected nothing closer than 2")
+	}
+	if _, _, ok := tree.AnyCloserThan(Word("mole"), 0); ok {
+		t.Error("expected nothing closer than 0")
+	}
+}
+
+func TestBKTree_SearchWithNearestMiss(t *testing.T) {
+	tree := new(BKTree)
+	for _, n := range []int{10, 14, 17} {
+		tree.Add(bigNumber(n))
+	}
+	// 17 is only 2 away but its bucket is pruned, so the miss is the root
+	results, miss, count := tree.SearchWithNearestMiss(bigNumber(15), 1)
+	if len(results) != 1 || miss.Value != bigNumber(10) || miss.Distance != 5 || count != 2 {
+		t.Errorf("expected 14 and a miss of 10 at 5, got: %v, %v after %d", results, miss, count)
+	}
+	if _, miss, _ := tree.SearchWithNearestMiss(bigNumber(15), 100); miss.Value != nil {
+		t.Errorf("expected no miss, got: %v", miss)
+	}
+}
+
+func TestBKTree_SearchMultiRadius(t *testing.T) {
+	tree := new(BKTree)
+	for n := 0; n < 50; n++ {
+		tree.Add(bigNumber((n * 7) % 50))
+	}
+	radii := []Distance{3, 1, 6}
+	bands, _ := tree.SearchMultiRadius(bigNumber(20), radii)
+	expected := [][]string{{"17", "18", "22", "23"}, {"19", "20", "21"}, {"14", "15", "16", "24", "25", "26"}}
+	for i, band := range bands {
+		if got := sortedStrings(band); !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("radius %d: expected: %v, got: %v", radii[i], expected[i], got)
+		}
+	}
+	_, count := tree.Search(bigNumber(20), 6)
+	if _, multiCount := tree.SearchMultiRadius(bigNumber(20), radii); multiCount != count {
+		t.Errorf("expected: %d, got: %d", count, multiCount)
+	}
+}
+
+func TestBKTree_SearchFaceted(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "some", "same", "soft", "some", "mole"})
+	if node := tree.Find(Word("some")); node.Duplicates != 2 {
+		t.Errorf("expected: %d, got: %d", 2, node.Duplicates)
+	}
+	facets, _ := tree.SearchFaceted(Word("sole"), 2)
+	expected := []Facet{{Word("some"), 3, 2}, {Word("mole"), 1, 2}}
+	if !reflect.DeepEqual(facets, expected) {
+		t.Errorf("expected: %v, got: %v", expected, facets)
+	}
+}
+
+func TestBKTree_SearchFilter(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	_, expectedCount := tree.Search(Word("sole"), 2)
+	results, count := tree.SearchFilter(Word("sole"), 2, func(v MetricTensor) bool {
+		return strings.HasPrefix(v.ToString(), "s")
+	})
+	if got := sortedStrings(results); !reflect.DeepEqual(got, []string{"some"}) || count != expectedCount {
+		t.Errorf("expected: [some] over %d nodes, got: %v over %d", expectedCount, got, count)
+	}
+}
+
+func TestBKTree_AddWithRootDistance(t *testing.T) {
+	calls := 0
+	word := func(w string) countingWord { return countingWord{Word(w), &calls} }
+	tree := new(BKTree)
+	tree.Add(word("some"))
+	tree.Add(word("soft"))
+	calls = 0
+	if err := tree.AddWithRootDistance(word("same"), word("some").DistanceFrom(word("same"))); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the root distance not to be computed again, got %d computations", calls)
+	}
+	if err := tree.Validate(); err != nil || tree.Size != 3 {
+		t.Errorf("expected a valid tree of size 3, got %d (%v)", tree.Size, err)
+	}
+
+	tree.MetricCheck = &MetricCheck{}
+	if err := tree.AddWithRootDistance(word("mole"), 1); !errors.Is(err, ErrWrongRootDistance) {
+		t.Errorf("expected: %v, got: %v", ErrWrongRootDistance, err)
+	}
+}
+
+func TestBKTree_SearchTargetCount(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 12)
+	results, radius, count := tree.SearchTargetCount(hashes[0], 5)
+	if len(results) < 5 {
+		t.Fatalf("expected at least %d results, got: %d", 5, len(results))
+	}
+	expected, _ := tree.Search(hashes[0], radius)
+	if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(results)) {
+		t.Errorf("expected: %v, got: %v", sortedStrings(expected), sortedStrings(results))
+	}
+	if fewer, _ := tree.Search(hashes[0], radius-1); len(fewer) >= 5 {
+		t.Errorf("expected radius %d to be the smallest one, %d results within %d", radius, len(fewer), radius-1)
+	}
+	if count > tree.Size {
+		t.Errorf("expected at most %d distance computations, got: %d", tree.Size, count)
+	}
+	all, _, _ := tree.SearchTargetCount(hashes[0], 5000)
+	if len(all) != tree.Size {
+		t.Errorf("expected: %d, got: %d", tree.Size, len(all))
+	}
+	// soft is visited but not pruned at radius 0, both values are still out of it
+	words := createNewTreeFromWords([]string{"some", "soft"})
+	if results, radius, _ := words.SearchTargetCount(Word("sxxe"), 2); len(results) != 2 || radius != 6 {
+		t.Errorf("expected: [some soft] within 6, got: %v within %d", results, radius)
+	}
+}
+
+func TestBKTree_CountDistinct(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"a", "ab", "abc", "d", "a", "ab"})
+	tree.Size = 42
+	if n := tree.CountDistinct(); n != 4 {
+		t.Errorf("expected: %d, got: %d", 4, n)
+	}
+	if n := new(BKTree).CountDistinct(); n != 0 {
+		t.Errorf("expected: %d, got: %d", 0, n)
+	}
+	tree.CalculateSize()
+	if tree.Size != 4 {
+		t.Errorf("expected: %d, got: %d", 4, tree.Size)
+	}
+}
+
+func TestBKTree_SearchMaxDepth(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"a", "ab", "abc", "d"})
+	results, count, truncated := tree.SearchMaxDepth(Word("d"), 10, 1)
+	if !truncated || count != 3 || len(results) != 3 {
+		t.Errorf("expected 3 results from 3 nodes with truncation, got: %v, %d, %v", results, count, truncated)
+	}
+	results, _, truncated = tree.SearchMaxDepth(Word("d"), 10, 2)
+	if truncated || len(results) != 4 {
+		t.Errorf("expected all 4 results without truncation, got: %v, %v", results, truncated)
+	}
+	if _, _, truncated := tree.SearchMaxDepth(Word("a"), 0, 0); truncated {
+		t.Error("expected no truncation when no child can match")
+	}
+}
+
+func TestBKTree_SearchWithProgress(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(5000, 14)
+	var reports []int
+	results, count := tree.SearchWithProgress(hashes[0], 64, func(visited int) {
+		reports = append(reports, visited)
+	})
+	if len(results) != tree.Size || count != tree.Size {
+		t.Errorf("expected: %d, got: %d results for %d visits", tree.Size, len(results), count)
+	}
+	expected := []int{1024, 2048, 3072, 4096, 5000}
+	if !reflect.DeepEqual(expected, reports) {
+		t.Errorf("expected: %v, got: %v", expected, reports)
+	}
+}
+
+func TestBKTree_SearchChecked_EmptyTree(t *testing.T) {
+	tree := new(BKTree)
+	if _, _, err := tree.SearchChecked(Word("a"), 1); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("expected: %v, got: %v", ErrEmptyTree, err)
+	}
+	if _, err := tree.Freeze().SearchHammingBatch([]Hamming64{1}, 1); !errors.Is(err, ErrEmptyTree) {
+		t.Errorf("expected: %v, got: %v", ErrEmptyTree, err)
+	}
+	tree.Add(Word("b"))
+	results, _, err := tree.SearchChecked(Word("a"), 1)
+	if err != nil || len(results) != 0 {
+		t.Errorf("expected no match and no error, got: %v, %v", results, err)
+	}
+}
+
+func TestBKTree_LinearScanBelow(t *testing.T) {
+	wordsList := []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"}
+	tree := createNewTreeFromWords(wordsList)
+	expected, _ := tree.Search(Word("sort"), 4)
+	tree.LinearScanBelow = 8
+	got, count := tree.Search(Word("sort"), 4)
+	if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || count != tree.Size {
+		t.Errorf("expected: %v over %d nodes, got: %v over %d", sortedStrings(expected), tree.Size, sortedStrings(got), count)
+	}
+}
+
+func benchmarkSmallTree(b *testing.B, size, linearScanBelow int) {
+	hashes, tree := makeRandomHammingTree(size, 1)
+	tree.LinearScanBelow = linearScanBelow
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(hashes[i%len(hashes)], 8)
+	}
+}
+
+func BenchmarkBKTree_Search_Small16(b *testing.B)      { benchmarkSmallTree(b, 16, 0) }
+func BenchmarkBKTree_Search_Small16Scan(b *testing.B)  { benchmarkSmallTree(b, 16, 17) }
+func BenchmarkBKTree_Search_Small64(b *testing.B)      { benchmarkSmallTree(b, 64, 0) }
+func BenchmarkBKTree_Search_Small64Scan(b *testing.B)  { benchmarkSmallTree(b, 64, 65) }
+func BenchmarkBKTree_Search_Small256(b *testing.B)     { benchmarkSmallTree(b, 256, 0) }
+func BenchmarkBKTree_Search_Small256Scan(b *testing.B) { benchmarkSmallTree(b, 256, 257) }
+
+func TestBKTree_Add_Nil(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft"})
+	if err := tree.Add(nil); !errors.Is(err, ErrNilValue) || tree.Size != 2 {
+		t.Errorf("expected: %v, got: %v", ErrNilValue, err)
+	}
+	if err := tree.AddWithRootDistance(nil, 1); !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected: %v, got: %v", ErrNilValue, err)
+	}
+	if results, _ := tree.Search(nil, 10); len(results) != 0 {
+		t.Errorf("expected no results, got: %v", results)
+	}
+
+	// a nil value slipped in by hand is skipped without pruning its children
+	tree.Root = &BkTreeNode{Children: map[Distance]*BkTreeNode{1: newbkTreeNode(Word("some"))}}
+	if results, _ := tree.Search(Word("some"), 0); len(results) != 1 {
+		t.Errorf("expected: [some], got: %v", results)
+	}
+	if results, _, _ := tree.SearchChecked(Word("some"), 0); len(results) != 1 {
+		t.Errorf("expected: [some], got: %v", results)
+	}
+	for _, err := range []error{tree.Validate(), tree.ValidateParallel()} {
+		if !errors.Is(err, ErrInvalidTree) || !errors.Is(err, ErrNilValue) {
+			t.Errorf("expected a nil value error, got: %v", err)
+		}
+	}
+}
+
+func TestBKTree_NilNodes(t *testing.T) {
+	newNilLeaf := func() *BKTree {
+		tree := createNewTreeFromWords([]string{"some", "soft"})
+		tree.Root.Children[4].Children[2] = &BkTreeNode{}
+		return tree
+	}
+	newNilRoot := func() *BKTree {
+		return &BKTree{Size: 2, Root: &BkTreeNode{Children: map[Distance]*BkTreeNode{
+			1: newbkTreeNode(Word("some")),
+			2: newbkTreeNode(Word("soft")),
+		}}}
+	}
+	nilLeaf, nilRoot := newNilLeaf(), newNilRoot()
+	expected := []string{"soft", "some"}
+	for name, build := range map[string]func() *BKTree{"nil leaf": newNilLeaf, "nil root": newNilRoot} {
+		tree := build()
+		check := func(fn string, got []MetricTensor) {
+			if !reflect.DeepEqual(sortedStrings(got), expected) {
+				t.Errorf("%s, %s: expected: %v, got: %v", name, fn, expected, sortedStrings(got))
+			}
+		}
+		values := func(matches []Match) []MetricTensor {
+			var vals []MetricTensor
+			for _, m := range matches {
+				vals = append(vals, m.Value)
+			}
+			return vals
+		}
+		got, _ := tree.Search(Word("some"), 4)
+		check("Search", got)
+		tree.Traversal = DepthFirst
+		got, _ = tree.Search(Word("some"), 4)
+		check("Search (depth first)", got)
+		got, _ = tree.SearchApprox(Word("some"), 4, 1)
+		check("SearchApprox", got)
+		got, _, _ = tree.SearchMaxDepth(Word("some"), 4, 1)
+		check("SearchMaxDepth", got)
+		got, _, _ = tree.SearchTargetCount(Word("some"), 2)
+		check("SearchTargetCount", got)
+		knn, _ := tree.SearchKNN(Word("some"), 2)
+		check("SearchKNN", values(knn))
+		knn, _ = tree.SearchKNNProgressive(Word("some"), 2, 0, nil)
+		check("SearchKNNProgressive", values(knn))
+		got = got[:0]
+		for v := range tree.Matches(Word("some"), 4) {
+			got = append(got, v)
+		}
+		check("Matches", got)
+		got, _ = tree.Freeze().Search(Word("some"), 4)
+		check("FrozenTree.Search", got)
+		if steps := tree.Explain(Word("some"), 4); len(steps) != 3 {
+			t.Errorf("%s, Explain: expected a step per node, got: %v", name, steps)
+		}
+		check("All", slices.Collect(tree.All()))
+		var sharded []MetricTensor
+		for _, shard := range tree.Shard(2) {
+			sharded = append(sharded, slices.Collect(shard.All())...)
+		}
+		check("Shard", sharded)
+		retained := build()
+		if removed := retained.RetainTopK(1); removed != 1 || !reflect.DeepEqual(sortedStrings(slices.Collect(retained.All())), []string{"soft"}) {
+			t.Errorf("%s, RetainTopK: expected [soft] with 1 removed, got: %v with %d", name, slices.Collect(retained.All()), removed)
+		}
+	}
+	if !nilLeaf.RebuildSubtree(Word("some"), rand.New(rand.NewSource(1))) || nilLeaf.Size != 2 {
+		t.Errorf("expected the subtree to be rebuilt, got size %d", nilLeaf.Size)
+	}
+	check := func(fn string, got []MetricTensor) {
+		if !reflect.DeepEqual(sortedStrings(got), expected) {
+			t.Errorf("%s: expected: %v, got: %v", fn, expected, sortedStrings(got))
+		}
+	}
+	check("RebuildSubtree", slices.Collect(nilLeaf.All()))
+	if nilRoot.RebuildSubtree(Word("some"), nil) {
+		t.Error("expected no value found below a nil root")
+	}
+
+	// a nil leaf is left out of the JSON, a nil value with children cannot be encoded
+	nilLeaf = newNilLeaf()
+	data, err := nilLeaf.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := FromJson(data, wordFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("ToJson", slices.Collect(decoded.All()))
+	if _, err := nilRoot.ToJson(); !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected a nil value error, got: %v", err)
+	}
+
+	editLeaf := &BKTree{}
+	editLeaf.Add(EditString("some"))
+	editLeaf.Add(EditString("soft"))
+	editLeaf.Root.Children[2].Children[1] = &BkTreeNode{}
+	editRoot := &BKTree{Root: &BkTreeNode{Children: map[Distance]*BkTreeNode{
+		1: newbkTreeNode(EditString("some")),
+		2: newbkTreeNode(EditString("soft")),
+	}}}
+	for name, tree := range map[string]*BKTree{"nil leaf": editLeaf, "nil root": editRoot} {
+		got, count := tree.SearchEditString(EditString("some"), 2)
+		if !reflect.DeepEqual(sortedStrings(got), expected) || count != 2 {
+			t.Errorf("%s, SearchEditString: expected: %v in 2 distances, got: %v in %d", name, expected, sortedStrings(got), count)
+		}
+	}
+	if err := NewForest(1).Add(nil); !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected: %v, got: %v", ErrNilValue, err)
+	}
+	if node := nilLeaf.Find(Word("soft")); node == nil {
+		t.Errorf("expected to find soft beside the nil leaf")
+	}
+	if node := nilRoot.Find(Word("soft")); node != nil {
+		t.Errorf("expected no value found below a nil root, got: %v", node.MetricTensor)
+	}
+	if err := nilRoot.Add(Word("same")); !errors.Is(err, ErrInvalidTree) || !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected a nil value error, got: %v", err)
+	}
+	if err := nilRoot.AddWithRootDistance(Word("same"), 1); !errors.Is(err, ErrInvalidTree) || !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected a nil value error, got: %v", err)
+	}
+}
+
+func TestBKTree_Len(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "same"})
+	if tree.Len() != 3 {
+		t.Errorf("expected: %d, got: %d", 3, tree.Len())
+	}
+	tree.RemoveNode(tree.Root)
+	if tree.Len() != 2 {
+		t.Errorf("expected: %d, got: %d", 2, tree.Len())
+	}
+	tree.Root.Children[100] = newbkTreeNode(Word("mole"))
+	tree.MarkDirty()
+	if tree.Len() != 3 || tree.Size != 3 {
+		t.Errorf("expected: %d, got: %d", 3, tree.Len())
+	}
+	other := createNewTreeFromWords([]string{"a", "b", "c", "d"})
+	tree.Root = other.Root
+	if tree.Len() != 4 {
+		t.Errorf("expected: %d, got: %d", 4, tree.Len())
+	}
+	if manual := (&BKTree{Root: other.Root}); manual.Len() != 4 {
+		t.Errorf("expected: %d, got: %d", 4, manual.Len())
+	}
+}
+
+func TestBKTree_MaxResults(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 15)
+	all, _ := tree.Search(hashes[0], 28)
+	if len(all) <= 10 {
+		t.Fatalf("expected more than 10 matches, got: %d", len(all))
+	}
+	tree.MaxResults = 10
+	check := func(name string, n int) {
+		if n != 10 {
+			t.Errorf("%s: expected: %d, got: %d", name, 10, n)
+		}
+	}
+	results, _ := tree.Search(hashes[0], 28)
+	check("Search", len(results))
+	results, _ = tree.SearchWithHint(hashes[0], 28, 100)
+	check("SearchWithHint", len(results))
+	results, _ = tree.SearchFilter(hashes[0], 28, func(MetricTensor) bool { return true })
+	check("SearchFilter", len(results))
+	results, _ = tree.SearchExhaustive(hashes[0], 28)
+	check("SearchExhaustive", len(results))
+	results, _ = tree.SearchWithProgress(hashes[0], 28, func(int) {})
+	check("SearchWithProgress", len(results))
+	knn, _ := tree.SearchKNN(hashes[0], 50)
+	check("SearchKNN", len(knn))
+	facets, _ := tree.SearchFaceted(hashes[0], 28)
+	check("SearchFaceted", len(facets))
+	results, _, _ = tree.SearchChecked(hashes[0], 28)
+	check("SearchChecked", len(results))
+	results, _ = tree.SearchApprox(hashes[0], 28, 1<<20)
+	check("SearchApprox", len(results))
+	results, _, _ = tree.SearchMaxDepth(hashes[0], 28, 100)
+	check("SearchMaxDepth", len(results))
+	results, _ = tree.SearchDedup(hashes[0], 28, MetricTensor.ToString)
+	check("SearchDedup", len(results))
+	results, _, _ = tree.SearchWithNearestMiss(hashes[0], 28)
+	check("SearchWithNearestMiss", len(results))
+	bands, _ := tree.SearchMultiRadius(hashes[0], []Distance{20, 28})
+	check("SearchMultiRadius", len(bands[0])+len(bands[1]))
+	results, _, _ = tree.SearchTargetCount(hashes[0], 50)
+	check("SearchTargetCount", len(results))
+	for i, m := range knn {
+		if d := results[i].DistanceFrom(hashes[0]); d != m.Distance {
+			t.Errorf("SearchTargetCount: expected the closest values, got distance %d at %d, not %d", d, i, m.Distance)
+		}
+	}
+	n := 0
+	for range tree.Matches(hashes[0], 28) {
+		n++
+	}
+	check("Matches", n)
+	frozen := tree.Freeze()
+	results, _ = frozen.Search(hashes[0], 28)
+	check("FrozenTree.Search", len(results))
+	results, _ = frozen.Compact().Search(hashes[0], 28)
+	check("FrozenTree.Search (compacted)", len(results))
+	tree.LinearScanBelow = 5000
+	results, _ = tree.Search(hashes[0], 28)
+	check("Search (linear scan)", len(results))
+}
+
+func TestBKTree_SearchRerank(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	// number of differing positions, the levenshtein distance is at most twice as much
+	positions := func(a, b MetricTensor) Distance {
+		x, y := string(a.(Word)), string(b.(Word))
+		if len(x) > len(y) {
+			x, y = y, x
+		}
+		d := Distance(len(y) - len(x))
+		for i := range x {
+			if x[i] != y[i] {
+				d += 1
+			}
+		}
+		return d
+	}
+	matches, count := tree.SearchRerank(Word("sort"), 2, positions, 1)
+	if len(matches) != 1 || matches[0] != (Match{Word("soft"), 1}) {
+		t.Errorf("expected: [{soft 1}], got: %v", matches)
+	}
+	if count == 0 || count > tree.Size {
+		t.Errorf("unexpected visited count: %d", count)
+	}
+	matches, _ = tree.SearchRerank(Word("sort"), 4, positions, 2)
+	if len(matches) != 4 || matches[0] != (Match{Word("soft"), 1}) {
+		t.Errorf("expected soft first among 4 matches, got: %v", matches)
+	}
+}
+
+func TestBKTree_Epsilon_Duplicates(t *testing.T) {
+	tree := &BKTree{Epsilon: 2}
+	for _, w := range []string{"some", "same", "soft", "sole", "some"} {
+		if err := tree.Add(Word(w)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tree.Size != 2 {
+		t.Errorf("expected same and sole to be duplicates of some, got size: %d", tree.Size)
+	}
+	if got := tree.Count(Word("same")); got != 4 {
+		t.Errorf("expected: %d, got: %d", 4, got)
+	}
+	if node := tree.Find(Word("mole")); node != nil {
+		t.Errorf("expected mole (4 or more from every value) not to be found, got: %v", node.MetricTensor)
+	}
+	results, _ := tree.Search(Word("soft"), 0)
+	if len(results) != 1 || results[0] != Word("soft") {
+		t.Errorf("expected: [soft], got: %v", results)
+	}
+}
+
+func TestBKTree_ChildrenCap(t *testing.T) {
+	for _, c := range []int{-1, 0, 16} {
+		tree := &BKTree{ChildrenCap: c}
+		r := rand.New(rand.NewSource(1))
+		hashes := make([]Hamming64, 500)
+		for i := range hashes {
+			hashes[i] = Hamming64(r.Uint64())
+			tree.Add(hashes[i])
+		}
+		if err := tree.Validate(); err != nil || tree.Size != 500 {
+			t.Errorf("cap %d: expected a valid tree of size 500, got %d (%v)", c, tree.Size, err)
+		}
+		if c < 0 {
+			for _, node := range collectNodes(tree.Root, nil) {
+				if len(node.Children) == 0 && node.Children != nil {
+					t.Fatalf("expected leaves without a map")
+				}
+			}
+		}
+		tree.RemoveNode(tree.Root)
+		results, _ := tree.Search(hashes[1], 0)
+		if len(results) != 1 || tree.Size != 499 {
+			t.Errorf("cap %d: expected %v to be found after a removal, got: %v", c, hashes[1], results)
+		}
+	}
+}
+
+func TestBKTree_SearchScored(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	matches, _ := tree.SearchScored(Word("some"), 3, nil)
+	expected := []ScoredMatch{{Word("some"), 0, 1}, {Word("same"), 2, 0.5}}
+	if !reflect.DeepEqual(expected, matches) {
+		t.Errorf("expected: %v, got: %v", expected, matches)
+	}
+	// a custom scorer ranking the farthest first
+	matches, _ = tree.SearchScored(Word("some"), 2, func(d Distance) float64 { return float64(d) })
+	if len(matches) != 2 || matches[0].Value != Word("same") || matches[0].Score != 2 {
+		t.Errorf("expected same first, got: %v", matches)
+	}
+}
+
+func TestBKTree_Version(t *testing.T) {
+	tree := new(BKTree)
+	last := tree.Version()
+	changed := func(what string) {
+		if v := tree.Version(); v <= last {
+			t.Errorf("%s: expected the version to grow past %d, got: %d", what, last, v)
+		} else {
+			last = v
+		}
+	}
+	tree.Add(Word("some"))
+	changed("add root")
+	tree.Add(Word("soft"))
+	changed("add")
+	tree.Add(Word("soft"))
+	changed("duplicate")
+	tree.Search(Word("some"), 4)
+	tree.Find(Word("soft"))
+	if tree.Version() != last {
+		t.Errorf("expected reads to keep the version: %d, got: %d", last, tree.Version())
+	}
+	tree.RemoveNode(tree.Find(Word("soft")))
+	changed("remove")
+	tree.MarkDirty()
+	changed("mark dirty")
+	tree.Add(Word("same"))
+	tree.RemoveBulk(func(val MetricTensor) bool { return val == Word("same") })
+	changed("bulk remove")
+}
+
+func TestBKTree_Frontier(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 8)
+	expected, expectedCount := tree.Search(hashes[0], 16)
+	for name, frontier := range map[string]func() FrontierStrategy{"fifo": FIFOFrontier, "lifo": LIFOFrontier, "best": BestFirstFrontier} {
+		tree.Frontier = frontier
+		got, count := tree.Search(hashes[0], 16)
+		if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || count != expectedCount {
+			t.Errorf("%s: expected %d results in %d, got %d in %d", name, len(expected), expectedCount, len(got), count)
+		}
+	}
+	// the best-first order finds the exact match before anything else
+	tree.Frontier, tree.MaxResults = BestFirstFrontier, 1
+	if got, _ := tree.Search(hashes[1], 30); len(got) != 1 || got[0] != hashes[1] {
+		t.Errorf("expected: [%v], got: %v", hashes[1], got)
+	}
+}
+
+func TestBKTree_SearchUnion(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 12)
+	queries := []MetricTensor{hashes[0], hashes[1] ^ 0xf, hashes[2] ^ 0xff00, hashes[0] ^ 1, nil}
+	expected := make(map[string]bool)
+	for _, q := range queries[:4] {
+		results, _ := tree.Search(q, 12)
+		for _, r := range results {
+			expected[r.ToString()] = true
+		}
+	}
+	got := sortedStrings(tree.SearchUnion(queries, 12))
+	if len(got) != len(expected) {
+		t.Errorf("expected %d distinct results, got %d", len(expected), len(got))
+	}
+	for i, s := range got {
+		if !expected[s] || i > 0 && got[i-1] == s {
+			t.Errorf("unexpected or repeated result: %s", s)
+		}
+	}
+	if results := tree.SearchUnion(nil, 12); len(results) != 0 {
+		t.Errorf("expected no results, got: %v", results)
+	}
+}
+
+func TestBKTree_SearchSorted(t *testing.T) {
+	hashes, _ := makeRandomHammingTree(2000, 41)
+	tree := &BKTree{Sequence: true}
+	index := make(map[MetricTensor]int)
+	for i, h := range hashes {
+		tree.Add(h)
+		index[h] = i
+	}
+	query := hashes[7] ^ 0xff
+	expected, _ := tree.Search(query, 20)
+	matches, _ := tree.SearchSorted(query, 20, TiesInInsertionOrder)
+	if len(matches) != len(expected) {
+		t.Fatalf("expected %d matches, got %d", len(expected), len(matches))
+	}
+	for i := 1; i < len(matches); i++ {
+		a, b := matches[i-1], matches[i]
+		if a.Distance > b.Distance || a.Distance == b.Distance && index[a.Value] > index[b.Value] {
+			t.Errorf("out of order: %v at %d before %v at %d", a.Value, a.Distance, b.Value, b.Distance)
+		}
+	}
+	if tree.Root.Seq != 1 {
+		t.Errorf("expected the root to be numbered 1, got %d", tree.Root.Seq)
+	}
+	tree.MaxResults = 3
+	capped, _ := tree.SearchSorted(query, 20, TiesInTraversalOrder)
+	if len(capped) != 3 || capped[2].Distance != matches[2].Distance {
+		t.Errorf("expected the 3 closest matches, got: %v", capped)
+	}
+}
+
+func TestBKTree_MeasureRecall(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 51)
+	exact, _ := tree.Search(hashes[3], 24)
+	if recall, missed := tree.MeasureRecall(hashes[3], 24, exact); recall != 1 || len(missed) != 0 {
+		t.Errorf("expected full recall, got: %v, missing %d", recall, len(missed))
+	}
+	approx, _ := tree.SearchApprox(hashes[3], 24, 1)
+	recall, missed := tree.MeasureRecall(hashes[3], 24, approx)
+	if recall >= 1 {
+		t.Errorf("expected SearchApprox to miss matches, got recall %v", recall)
+	}
+	if expected := float64(len(approx)) / float64(len(exact)); recall != expected || len(missed) != len(exact)-len(approx) {
+		t.Errorf("expected recall %v, got: %v, missing %d", expected, recall, len(missed))
+	}
+	if recall, _ := tree.MeasureRecall(hashes[3], -1, nil); recall != 1 {
+		t.Errorf("expected full recall without matches, got: %v", recall)
+	}
+}
+
+func TestBKTree_SearchRadiusFunc(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 81)
+	root := tree.Root.MetricTensor
+	radiusFunc := func(rootDist Distance) Distance { return rootDist / 3 }
+	query := hashes[9] ^ 0x7
+	var expected []MetricTensor
+	for _, h := range hashes {
+		if query.DistanceFrom(h) <= radiusFunc(root.DistanceFrom(h)) {
+			expected = append(expected, h)
+		}
+	}
+	got, count := tree.SearchRadiusFunc(query, radiusFunc)
+	if !reflect.DeepEqual(sortedStrings(got), sortedStrings(expected)) {
+		t.Errorf("expected %d results, got %d", len(expected), len(got))
+	}
+	if count >= tree.Size {
+		t.Errorf("expected pruning, got %d distances for %d values", count, tree.Size)
+	}
+	constant, _ := tree.Search(query, 12)
+	if got, _ := tree.SearchRadiusFunc(query, func(Distance) Distance { return 12 }); !reflect.DeepEqual(sortedStrings(got), sortedStrings(constant)) {
+		t.Errorf("expected a constant radius to match Search: %d vs %d results", len(got), len(constant))
+	}
+}
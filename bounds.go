@@ -0,0 +1,65 @@
+package go_bk_tree
+
+import "math"
+
+const (
+	maxDistance = Distance(math.MaxInt)
+	minDistance = Distance(math.MinInt)
+)
+
+// InvalidDistance is returned by DistanceFromFloat for NaN, like any negative
+// distance it is rejected by Add and SearchChecked
+const InvalidDistance Distance = -1
+
+// DistanceFromFloat rounds a float distance to a Distance. Values too large for a
+// Distance (including +Inf) saturate to the largest one, NaN and negative values
+// become InvalidDistance so that a buggy float metric is caught instead of being
+// converted to an arbitrary bucket.
+func DistanceFromFloat(f float64) Distance {
+	if math.IsNaN(f) || f < 0 {
+		return InvalidDistance
+	}
+	if f >= math.MaxInt {
+		return maxDistance
+	}
+	return Distance(math.Round(f))
+}
+
+// searchWindow returns the [low, high] range of child buckets that may hold values
+// within radius of a query dist away from their parent. The bounds saturate instead
+// of wrapping around, so that huge distances or radii never skip children.
+func searchWindow(dist, radius Distance) (low, high Distance) {
+	return subDistance(dist, radius), addDistance(dist, radius)
+}
+
+// addDistance returns a + b clamped to the range of Distance
+func addDistance(a, b Distance) Distance {
+	c := a + b
+	if b > 0 && c < a {
+		return maxDistance
+	}
+	if b < 0 && c > a {
+		return minDistance
+	}
+	return c
+}
+
+// subDistance returns a - b clamped to the range of Distance
+func subDistance(a, b Distance) Distance {
+	c := a - b
+	if b > 0 && c > a {
+		return minDistance
+	}
+	if b < 0 && c < a {
+		return maxDistance
+	}
+	return c
+}
+
+// diffDistance returns |a - b| clamped to the range of Distance
+func diffDistance(a, b Distance) Distance {
+	if a < b {
+		a, b = b, a
+	}
+	return subDistance(a, b)
+}
@@ -0,0 +1,130 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// bigNumber uses the absolute difference as distance, it reaches math.MaxInt between 0 and math.MaxInt
+type bigNumber int
+
+func (n bigNumber) DistanceFrom(other MetricTensor) Distance {
+	return diffDistance(Distance(n), Distance(other.(bigNumber)))
+}
+
+func (n bigNumber) ToString() string {
+	return strconv.Itoa(int(n))
+}
+
+func TestSearchWindow(t *testing.T) {
+	cases := []struct {
+		dist, radius, low, high Distance
+	}{
+		{5, 2, 3, 7},
+		{0, 3, -3, 3},
+		{maxDistance, 1, maxDistance - 1, maxDistance},
+		{maxDistance - 1, maxDistance, -1, maxDistance},
+		{0, maxDistance, -maxDistance, maxDistance},
+		{1, minDistance, maxDistance, minDistance + 1},
+	}
+	for _, c := range cases {
+		if low, high := searchWindow(c.dist, c.radius); low != c.low || high != c.high {
+			t.Errorf("searchWindow(%d, %d): expected: [%d, %d], got: [%d, %d]", c.dist, c.radius, c.low, c.high, low, high)
+		}
+	}
+	if d := diffDistance(minDistance, maxDistance); d != maxDistance {
+		t.Errorf("expected: %d, got: %d", maxDistance, d)
+	}
+}
+
+func TestBKTree_Search_HugeDistances(t *testing.T) {
+	tree := new(BKTree)
+	nums := []bigNumber{math.MaxInt, 0, math.MaxInt / 2, 1, math.MaxInt - 1}
+	for _, n := range nums {
+		tree.Add(n)
+	}
+	for _, query := range []bigNumber{0, math.MaxInt} {
+		results, _ := tree.Search(query, math.MaxInt)
+		if len(results) != len(nums) {
+			t.Errorf("query %d: expected: %d, got: %d", query, len(nums), len(results))
+		}
+		exact, _ := tree.SearchExact(query, math.MaxInt)
+		if len(exact) != 1 {
+			t.Errorf("query %d: expected: %d, got: %d", query, 1, len(exact))
+		}
+		frozen, _ := tree.Freeze().Search(query, math.MaxInt)
+		if len(frozen) != len(nums) {
+			t.Errorf("query %d: expected: %d, got: %d", query, len(nums), len(frozen))
+		}
+	}
+	if results, _ := tree.SearchKNN(bigNumber(math.MaxInt), 2); len(results) != 2 || results[1].Distance != 1 {
+		t.Errorf("unexpected results: %v", results)
+	}
+}
+
+func TestDistanceFromFloat(t *testing.T) {
+	cases := []struct {
+		f        float64
+		expected Distance
+	}{
+		{0, 0},
+		{2.4, 2},
+		{2.5, 3},
+		{math.NaN(), InvalidDistance},
+		{-1, InvalidDistance},
+		{math.Inf(1), maxDistance},
+		{1e300, maxDistance},
+	}
+	for _, c := range cases {
+		if d := DistanceFromFloat(c.f); d != c.expected {
+			t.Errorf("DistanceFromFloat(%v): expected: %d, got: %d", c.f, c.expected, d)
+		}
+	}
+}
+
+// flakyFloat is a float metric returning NaN for one specific value
+type flakyFloat float64
+
+func (f flakyFloat) DistanceFrom(other MetricTensor) Distance {
+	o := other.(flakyFloat)
+	if f == 13 || o == 13 {
+		return DistanceFromFloat(math.NaN())
+	}
+	return DistanceFromFloat(math.Abs(float64(f - o)))
+}
+
+func (f flakyFloat) ToString() string {
+	return strconv.FormatFloat(float64(f), 'g', -1, 64)
+}
+
+func TestBKTree_NaNDistance(t *testing.T) {
+	tree := new(BKTree)
+	for i := 0; i < 20; i++ {
+		err := tree.Add(flakyFloat(i))
+		if i == 13 {
+			if !errors.Is(err, ErrNegativeDistance) {
+				t.Errorf("expected: %v, got: %v", ErrNegativeDistance, err)
+			}
+		} else if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if tree.Size != 19 {
+		t.Errorf("expected: %d, got: %d", 19, tree.Size)
+	}
+	if _, _, err := tree.SearchChecked(flakyFloat(13), 2); !errors.Is(err, ErrNegativeDistance) {
+		t.Errorf("expected: %v, got: %v", ErrNegativeDistance, err)
+	}
+	results, _, err := tree.SearchChecked(flakyFloat(5), 2)
+	if err != nil || len(results) != 5 {
+		t.Errorf("expected 5 results, got: %v (%v)", results, err)
+	}
+
+	seqs := new(BKTree)
+	seqs.Add(Sequence{1, 2, 3})
+	if err := seqs.Add(Sequence{1, math.NaN(), 3}); !errors.Is(err, ErrNegativeDistance) {
+		t.Errorf("expected: %v, got: %v", ErrNegativeDistance, err)
+	}
+}
@@ -0,0 +1,105 @@
+package go_bk_tree
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// BuildFromSlice builds a tree from vals using a randomly picked value as the root,
+// the remaining values are added in their original order. Pass a *rand.Rand to make
+// the choice reproducible, a nil rng falls back to a time-seeded source. It stops at
+// the first value rejected by Add.
+func BuildFromSlice(vals []MetricTensor, rng *rand.Rand) (*BKTree, error) {
+	tree := new(BKTree)
+	if len(vals) == 0 {
+		return tree, nil
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	pivot := rng.Intn(len(vals))
+	if err := tree.Add(vals[pivot]); err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if i == pivot {
+			continue
+		}
+		if err := tree.Add(v); err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// BuildFromWeighted builds a tree from the keys of m, e.g. a word-frequency dictionary,
+// each key being added as factory(key) with its weight recorded as if it had been added
+// that many times: Count returns the weight (the sum of the weights of keys that are
+// duplicates of each other) and SearchFaceted ranks the matches by it. Keys are added
+// in sorted order so the tree does not depend on the map order. Keys with a weight
+// below 1 and values rejected by Add are skipped.
+func BuildFromWeighted(m map[string]int, factory func(string) MetricTensor) *BKTree {
+	keys := make([]string, 0, len(m))
+	for key, weight := range m {
+		if weight > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	tree := new(BKTree)
+	for _, key := range keys {
+		val := factory(key)
+		if err := tree.Add(val); err != nil {
+			continue
+		}
+		// Add counted one occurrence already
+		tree.Find(val).Duplicates += m[key] - 1
+	}
+	return tree
+}
+
+// AddSorted adds vals to the tree ordered by their distance from the current root
+// (the first value becomes the root of an empty tree), so that values landing in
+// the same root bucket are inserted consecutively and their descent touches the
+// same nodes while they are still hot in cache. The ordering costs one extra
+// distance computation per value. Notice: on random short words (see
+// BenchmarkBKTree_AddSorted vs BenchmarkBKTree_Add) this turned out ~15% slower
+// than plain Add, the extra distance computation outweighs the cache effect.
+// It fails with ErrNilValue, adding nothing, when vals holds a nil value, and
+// otherwise stops at the first value rejected by Add.
+func (tree *BKTree) AddSorted(vals []MetricTensor) error {
+	for i, v := range vals {
+		if v == nil {
+			return fmt.Errorf("%w at index %d", ErrNilValue, i)
+		}
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+	if tree.Root == nil {
+		if err := tree.Add(vals[0]); err != nil {
+			return err
+		}
+		vals = vals[1:]
+	}
+	type entry struct {
+		val  MetricTensor
+		dist Distance
+	}
+	entries := make([]entry, len(vals))
+	for i, v := range vals {
+		entries[i].val = v
+		if tree.Root.MetricTensor != nil {
+			entries[i].dist = tree.Root.DistanceFrom(v)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].dist < entries[j].dist })
+	for _, e := range entries {
+		if err := tree.Add(e.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package go_bk_tree
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestBuildFromSlice(t *testing.T) {
+	hashes, _ := makeRandomHammingTree(500, 3)
+	vals := make([]MetricTensor, len(hashes))
+	for i, h := range hashes {
+		vals[i] = h
+	}
+	a, err := BuildFromSlice(vals, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := BuildFromSlice(vals, rand.New(rand.NewSource(42)))
+	if a.Size != len(vals) {
+		t.Errorf("expected: %d, got: %d", len(vals), a.Size)
+	}
+	ja, _ := a.ToJson()
+	jb, _ := b.ToJson()
+	if !bytes.Equal(ja, jb) {
+		t.Error("expected trees built with the same seed to be identical")
+	}
+	if tree, _ := BuildFromSlice(nil, nil); tree.Root != nil {
+		t.Error("expected an empty tree")
+	}
+	if _, err := BuildFromSlice([]MetricTensor{nil}, nil); !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected the rejected pivot to fail with %v, got: %v", ErrNilValue, err)
+	}
+}
+
+func TestBuildFromWeighted(t *testing.T) {
+	weights := map[string]int{"some": 10, "soft": 3, "same": 7, "sorted": 0}
+	tree := BuildFromWeighted(weights, func(s string) MetricTensor { return Word(s) })
+	if tree.Size != 3 {
+		t.Errorf("expected: %d, got: %d", 3, tree.Size)
+	}
+	for w, weight := range map[string]int{"some": 10, "soft": 3, "same": 7, "sorted": 0, "mole": 0} {
+		if got := tree.Count(Word(w)); got != weight {
+			t.Errorf("%s: expected: %d, got: %d", w, weight, got)
+		}
+	}
+	facets, _ := tree.SearchFaceted(Word("some"), 4)
+	if len(facets) != 3 || facets[0].Value != Word("some") || facets[1].Value != Word("same") || facets[2].Count != 3 {
+		t.Errorf("expected the matches ranked by weight, got: %v", facets)
+	}
+}
+
+func TestBKTree_AddSorted(t *testing.T) {
+	wordsList := []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon", "some"}
+	vals := make([]MetricTensor, len(wordsList))
+	for i, w := range wordsList {
+		vals[i] = Word(w)
+	}
+	tree := new(BKTree)
+	if err := tree.AddSorted(vals); err != nil {
+		t.Fatal(err)
+	}
+	if rootVal := string(tree.Root.MetricTensor.(Word)); rootVal != "some" {
+		t.Errorf("expected: %s, got: %s", "some", rootVal)
+	}
+	if tree.Size != 7 {
+		t.Errorf("expected: %d, got: %d", 7, tree.Size)
+	}
+	for _, bad := range [][]MetricTensor{{nil, Word("some")}, {Word("some"), nil}} {
+		empty := new(BKTree)
+		if err := empty.AddSorted(bad); !errors.Is(err, ErrNilValue) || empty.Size != 0 {
+			t.Errorf("expected %v with nothing added, got: %v and size %d", ErrNilValue, err, empty.Size)
+		}
+	}
+}
+
+func makeRandomWords(size int, seed int64) []MetricTensor {
+	r := rand.New(rand.NewSource(seed))
+	vals := make([]MetricTensor, size)
+	for i := range vals {
+		w := make([]byte, 4+r.Intn(6))
+		for j := range w {
+			w[j] = byte('a' + r.Intn(6))
+		}
+		vals[i] = Word(w)
+	}
+	return vals
+}
+
+func BenchmarkBKTree_Add(b *testing.B) {
+	vals := makeRandomWords(20000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree := new(BKTree)
+		for _, v := range vals {
+			tree.Add(v)
+		}
+	}
+}
+
+func benchmarkAddChildrenCap(b *testing.B, childrenCap int) {
+	r := rand.New(rand.NewSource(1))
+	vals := make([]Hamming64, 20000)
+	for i := range vals {
+		vals[i] = Hamming64(r.Uint64())
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree := &BKTree{ChildrenCap: childrenCap}
+		for _, v := range vals {
+			tree.Add(v)
+		}
+	}
+}
+
+func BenchmarkBKTree_Add_ChildrenCap0(b *testing.B)    { benchmarkAddChildrenCap(b, 0) }
+func BenchmarkBKTree_Add_ChildrenCap16(b *testing.B)   { benchmarkAddChildrenCap(b, 16) }
+func BenchmarkBKTree_Add_ChildrenCapLazy(b *testing.B) { benchmarkAddChildrenCap(b, -1) }
+
+func BenchmarkBKTree_AddSorted(b *testing.B) {
+	vals := makeRandomWords(20000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree := new(BKTree)
+		tree.AddSorted(vals)
+	}
+}
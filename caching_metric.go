@@ -0,0 +1,64 @@
+package go_bk_tree
+
+import "sync"
+
+// DistanceCache is a size-bounded LRU cache of pairwise distances shared by CachingMetric
+// values, it is safe for concurrent use
+type DistanceCache struct {
+	mu     sync.Mutex
+	cache  *lru[[2]string, Distance]
+	hits   uint64
+	misses uint64
+}
+
+// NewDistanceCache creates a cache holding at most capacity distances
+func NewDistanceCache(capacity int) *DistanceCache {
+	return &DistanceCache{cache: newLRU[[2]string, Distance](capacity)}
+}
+
+// Stats returns the number of cache hits and misses so far
+func (c *DistanceCache) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// CachingMetric decorates a MetricTensor whose distance is expensive to compute, distances
+// are looked up in Cache by the ToString of both values before computing the real one.
+// The metric is assumed to be symmetric, and values with the same ToString must be equal.
+//
+// Example:
+//
+//	cache := NewDistanceCache(1 << 16)
+//	tree.Add(CachingMetric{MetricTensor: Word("some"), Cache: cache})
+//	tree.Search(CachingMetric{MetricTensor: Word("sort"), Cache: cache}, 2)
+type CachingMetric struct {
+	MetricTensor
+	Cache *DistanceCache
+}
+
+func (m CachingMetric) DistanceFrom(other MetricTensor) Distance {
+	if o, ok := other.(CachingMetric); ok {
+		other = o.MetricTensor
+	}
+	key := [2]string{m.MetricTensor.ToString(), other.ToString()}
+	if key[0] > key[1] {
+		key[0], key[1] = key[1], key[0]
+	}
+	m.Cache.mu.Lock()
+	dist, ok := m.Cache.cache.get(key)
+	if ok {
+		m.Cache.hits += 1
+	} else {
+		m.Cache.misses += 1
+	}
+	m.Cache.mu.Unlock()
+	if ok {
+		return dist
+	}
+	dist = m.MetricTensor.DistanceFrom(other)
+	m.Cache.mu.Lock()
+	m.Cache.cache.put(key, dist)
+	m.Cache.mu.Unlock()
+	return dist
+}
@@ -0,0 +1,54 @@
+package go_bk_tree
+
+import "testing"
+
+type countingWord struct {
+	Word
+	calls *int
+}
+
+func (w countingWord) DistanceFrom(other MetricTensor) Distance {
+	*w.calls += 1
+	return w.Word.DistanceFrom(other.(countingWord).Word)
+}
+
+func TestCachingMetric(t *testing.T) {
+	calls := 0
+	cache := NewDistanceCache(100)
+	wrap := func(w string) MetricTensor {
+		return CachingMetric{MetricTensor: countingWord{Word(w), &calls}, Cache: cache}
+	}
+	tree := new(BKTree)
+	for _, w := range []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"} {
+		tree.Add(wrap(w))
+	}
+	first, _ := tree.Search(wrap("sort"), 2)
+	callsAfterFirst := calls
+	second, _ := tree.Search(wrap("sort"), 2)
+	if calls != callsAfterFirst {
+		t.Errorf("expected no new distance computations, got: %d", calls-callsAfterFirst)
+	}
+	if len(first) != len(second) {
+		t.Errorf("expected: %d, got: %d", len(first), len(second))
+	}
+	if hits, misses := cache.Stats(); hits == 0 || misses != uint64(calls) {
+		t.Errorf("unexpected stats: %d hits, %d misses for %d computations", hits, misses, calls)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	c := newLRU[int, int](2)
+	c.put(1, 1)
+	c.put(2, 2)
+	c.get(1)
+	c.put(3, 3)
+	if _, ok := c.get(2); ok {
+		t.Error("expected least recently used key to be evicted")
+	}
+	if v, ok := c.get(1); !ok || v != 1 {
+		t.Errorf("expected: %d, got: %d", 1, v)
+	}
+	if c.len() != 2 {
+		t.Errorf("expected: %d, got: %d", 2, c.len())
+	}
+}
@@ -0,0 +1,90 @@
+package go_bk_tree
+
+// Gauge, Counter and Observer are the parts of the metric types of the Prometheus
+// client used by Collector (prometheus.Gauge, prometheus.Counter and
+// prometheus.Histogram satisfy them), so that this package imports no metrics library
+type Gauge interface {
+	Set(float64)
+}
+
+type Counter interface {
+	Add(float64)
+}
+
+type Observer interface {
+	Observe(float64)
+}
+
+// Collector exports the statistics of a tree to metrics: Attach makes the searches
+// update the counters and the latency histogram, and Update sets the gauges, e.g. from
+// a scrape callback or a ticker. Any metric can be nil.
+//
+// Example:
+//
+//	c := &Collector{Size: sizeGauge, SearchLatency: latencyHistogram}
+//	c.Attach(tree)
+//	...
+//	c.Update(tree)
+type Collector struct {
+	// Size is the number of values
+	Size Gauge
+	// Height is the number of levels, a chain of values is as high as it is long
+	Height Gauge
+	// Searches counts the calls to Search and SearchWithHint
+	Searches Counter
+	// DistanceComputations counts the distances computed by these searches, its rate is
+	// the number of distance computations per second
+	DistanceComputations Counter
+	// SearchLatency observes the duration of these searches in seconds
+	SearchLatency Observer
+}
+
+// Attach installs Hooks on tree reporting its searches to c, keeping the hooks already
+// set. It must be called before the tree is shared with other goroutines.
+func (c *Collector) Attach(tree *BKTree) {
+	var hooks Hooks
+	if tree.Hooks != nil {
+		hooks = *tree.Hooks
+	}
+	next := hooks.OnSearch
+	hooks.OnSearch = func(val MetricTensor, radius Distance, stats SearchStats) {
+		if c.Searches != nil {
+			c.Searches.Add(1)
+		}
+		if c.DistanceComputations != nil {
+			c.DistanceComputations.Add(float64(stats.Visited))
+		}
+		if c.SearchLatency != nil {
+			c.SearchLatency.Observe(stats.Duration.Seconds())
+		}
+		if next != nil {
+			next(val, radius, stats)
+		}
+	}
+	tree.Hooks = &hooks
+}
+
+// Update sets the Size and Height gauges from tree, computing the height walks the
+// whole tree. It reads the tree, so it needs the same locking as a search.
+func (c *Collector) Update(tree *BKTree) {
+	if c.Size != nil {
+		c.Size.Set(float64(tree.Size))
+	}
+	if c.Height != nil {
+		c.Height.Set(float64(height(tree.Root)))
+	}
+}
+
+// height returns the number of levels of the subtree rooted at node
+func height(node *BkTreeNode) int {
+	if node == nil {
+		return 0
+	}
+	h := 0
+	for _, child := range node.Children {
+		if ch := height(child); ch > h {
+			h = ch
+		}
+	}
+	return h + 1
+}
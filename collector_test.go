@@ -0,0 +1,42 @@
+package go_bk_tree
+
+import "testing"
+
+type testMetric struct {
+	value   float64
+	samples int
+}
+
+func (m *testMetric) Set(v float64) { m.value = v }
+
+func (m *testMetric) Add(v float64) { m.value += v }
+
+func (m *testMetric) Observe(v float64) {
+	m.value += v
+	m.samples++
+}
+
+func TestCollector(t *testing.T) {
+	var size, height, searches, distances, latency testMetric
+	c := &Collector{Size: &size, Height: &height, Searches: &searches, DistanceComputations: &distances, SearchLatency: &latency}
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	var hooked int
+	tree.Hooks = &Hooks{OnSearch: func(MetricTensor, Distance, SearchStats) { hooked++ }}
+	c.Attach(tree)
+	_, count1 := tree.Search(Word("sort"), 2)
+	_, count2 := tree.SearchWithHint(Word("some"), 4, 10)
+	if searches.value != 2 || latency.samples != 2 || hooked != 2 {
+		t.Errorf("expected 2 searches, got: %v, %d, %d", searches.value, latency.samples, hooked)
+	}
+	if distances.value != float64(count1+count2) {
+		t.Errorf("expected: %d, got: %v", count1+count2, distances.value)
+	}
+	c.Update(tree)
+	if size.value != 7 || height.value != float64(len(tree.Levels())) {
+		t.Errorf("expected size 7 and height %d, got: %v, %v", len(tree.Levels()), size.value, height.value)
+	}
+	c.Update(new(BKTree))
+	if size.value != 0 || height.value != 0 {
+		t.Errorf("expected an empty tree, got: %v, %v", size.value, height.value)
+	}
+}
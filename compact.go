@@ -0,0 +1,88 @@
+package go_bk_tree
+
+import (
+	"encoding/binary"
+)
+
+// Compact returns a copy of the frozen tree in which structurally identical
+// subtrees are stored only once (hash-consing): two subtrees are identical when
+// their values have the same ToString, they sit at the same distance from their
+// parents and their children are identical in turn. A tree built by Add stores
+// every value once and has nothing to share: the saving is only for data that
+// indexes the same values under several branches, e.g. trees decoded (without
+// Strict) from a serialized form with redundant entries, which Validate reports
+// as invalid since a value cannot sit in two buckets of a node. Sharing is
+// only safe because a FrozenTree is never mutated, Size is left untouched as it
+// counts logical nodes.
+func (ft *FrozenTree) Compact() *FrozenTree {
+	type nodeKey struct {
+		value string
+		dist  Distance
+		rng   int
+	}
+	n := len(ft.nodes)
+	compacted := &FrozenTree{Size: ft.Size, MaxResults: ft.MaxResults}
+	if n == 0 {
+		return compacted
+	}
+	// canonical ids are assigned bottom-up, children always come after their parent
+	canonical := make([]int, n)
+	nodeIDs := make(map[nodeKey]int)
+	rangeIDs := make(map[string]int)
+	var reprs []int     // canonical node id -> index of a representative in ft.nodes
+	var rangeOf []int   // canonical node id -> canonical child range id
+	var rangeReps []int // canonical range id -> representative parent node index
+	buf := make([]byte, 0, 64)
+	for i := n - 1; i >= 0; i-- {
+		node := ft.nodes[i]
+		buf = buf[:0]
+		for child := node.first; child < node.last; child++ {
+			buf = binary.AppendUvarint(buf, uint64(canonical[child]))
+		}
+		rng, ok := rangeIDs[string(buf)]
+		if !ok {
+			rng = len(rangeReps)
+			rangeIDs[string(buf)] = rng
+			rangeReps = append(rangeReps, i)
+		}
+		key := nodeKey{ft.values[i].ToString(), node.dist, rng}
+		id, ok := nodeIDs[key]
+		if !ok {
+			id = len(reprs)
+			nodeIDs[key] = id
+			reprs = append(reprs, i)
+			rangeOf = append(rangeOf, rng)
+		}
+		canonical[i] = id
+	}
+
+	emitted := make([]int32, len(rangeReps))
+	for r := range emitted {
+		emitted[r] = -1
+	}
+	ids := []int{canonical[0]}
+	compacted.nodes = append(compacted.nodes, frozenNode{})
+	compacted.values = append(compacted.values, ft.values[0])
+	for i := 0; i < len(compacted.nodes); i++ {
+		rng := rangeOf[ids[i]]
+		if emitted[rng] < 0 {
+			emitted[rng] = int32(len(compacted.nodes))
+			rep := ft.nodes[rangeReps[rng]]
+			for child := rep.first; child < rep.last; child++ {
+				ids = append(ids, canonical[child])
+				compacted.nodes = append(compacted.nodes, frozenNode{dist: ft.nodes[child].dist})
+				compacted.values = append(compacted.values, ft.values[reprs[canonical[child]]])
+			}
+		}
+		rep := ft.nodes[rangeReps[rng]]
+		compacted.nodes[i].first = emitted[rng]
+		compacted.nodes[i].last = emitted[rng] + (rep.last - rep.first)
+	}
+	if ft.hashes != nil {
+		compacted.hashes = make([]uint64, len(compacted.values))
+		for i, v := range compacted.values {
+			compacted.hashes[i] = uint64(v.(Hamming64))
+		}
+	}
+	return compacted
+}
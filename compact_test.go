@@ -0,0 +1,119 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func frozenTreeBytes(ft *FrozenTree) uintptr {
+	return uintptr(len(ft.nodes))*unsafe.Sizeof(frozenNode{}) +
+		uintptr(len(ft.values))*unsafe.Sizeof(MetricTensor(nil)) +
+		uintptr(len(ft.hashes))*unsafe.Sizeof(uint64(0))
+}
+
+// thawFrozen rebuilds a BKTree from the arrays of a frozen tree, the nodes of a child
+// range shared by Compact being shared as well
+func thawFrozen(ft *FrozenTree) *BKTree {
+	tree := &BKTree{Size: ft.Size}
+	if len(ft.nodes) == 0 {
+		return tree
+	}
+	nodes := make([]*BkTreeNode, len(ft.nodes))
+	var thaw func(i int32) *BkTreeNode
+	thaw = func(i int32) *BkTreeNode {
+		if nodes[i] == nil {
+			nodes[i] = newbkTreeNode(ft.values[i])
+			for child := ft.nodes[i].first; child < ft.nodes[i].last; child++ {
+				nodes[i].Children[ft.nodes[child].dist] = thaw(child)
+			}
+		}
+		return nodes[i]
+	}
+	tree.Root = thaw(0)
+	return tree
+}
+
+func TestFrozenTree_Compact(t *testing.T) {
+	// a branch built by Add, decoded under two buckets of the root as a redundant
+	// index could hold it: the copies are identical subtrees
+	branch := new(BKTree)
+	for _, w := range []string{"abc", "abcd", "abcde", "abd", "abcdef", "bbc"} {
+		branch.Add(Word(w))
+	}
+	if err := branch.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := branch.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(fmt.Sprintf(`["a",{"4":%s,"6":%s}]`, encoded, encoded))
+	tree, err := FromJson(data, wordFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a value is the same distance away from every node, so it cannot be stored
+	// under two buckets of one node: the redundant tree always breaks the invariant
+	if err := tree.Validate(); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("expected %v, got: %v", ErrInvalidTree, err)
+	}
+	if _, err := FromJsonWithOptions(data, wordFactory, DecodeOptions{Strict: true}); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("expected the strict decoding to fail with %v, got: %v", ErrInvalidTree, err)
+	}
+
+	frozen := tree.Freeze()
+	compacted := frozen.Compact()
+	before, after := frozenTreeBytes(frozen), frozenTreeBytes(compacted)
+	t.Logf("frozen tree: %d nodes, %d bytes; compacted: %d nodes, %d bytes",
+		len(frozen.nodes), before, len(compacted.nodes), after)
+	if after >= before {
+		t.Errorf("expected compacted tree to be smaller than %d bytes, got: %d", before, after)
+	}
+	if compacted.Size != frozen.Size {
+		t.Errorf("expected: %d, got: %d", frozen.Size, compacted.Size)
+	}
+	if thawed := thawFrozen(compacted); !EqualStructure(thawed, tree) || !errors.Is(thawed.Validate(), ErrInvalidTree) {
+		t.Error("expected the compacted tree to hold the same nodes")
+	}
+	for _, q := range []string{"abc", "abcd", "a", "bbc"} {
+		expected, _ := frozen.Search(Word(q), 3)
+		got, _ := compacted.Search(Word(q), 3)
+		if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) {
+			t.Errorf("expected: %v, got: %v", sortedStrings(expected), sortedStrings(got))
+		}
+	}
+}
+
+func TestFrozenTree_Compact_Valid(t *testing.T) {
+	// a tree built by Add stores every value once, it has nothing to share
+	tree := new(BKTree)
+	words := makeRandomWords(2000, 41)
+	for _, w := range words {
+		tree.Add(w)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	frozen := tree.Freeze()
+	compacted := frozen.Compact()
+	if before, after := frozenTreeBytes(frozen), frozenTreeBytes(compacted); after != before {
+		t.Errorf("expected: %d bytes, got: %d", before, after)
+	}
+	thawed := thawFrozen(compacted)
+	if err := thawed.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !EqualStructure(thawed, tree) {
+		t.Error("expected the compacted tree to hold the same nodes")
+	}
+	for _, q := range words[:20] {
+		expected, _ := tree.Search(q, 4)
+		got, _ := compacted.Search(q, 4)
+		if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) {
+			t.Errorf("%v: expected: %v, got: %v", q, sortedStrings(expected), sortedStrings(got))
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"strings"
+)
+
+// Composite is a MetricTensor made of several fields (e.g. the name and the category of
+// a record), its distance is the weighted sum of the distances between each pair of
+// fields. A nonnegative weighted sum of metrics is a metric, so the pruning of Search
+// stays correct as long as every field is a true metric and no weight is negative.
+// All the Composites of a tree must have the same fields in the same order, the
+// receiver's Weights are used.
+type Composite struct {
+	Fields  []MetricTensor
+	Weights []Distance
+}
+
+// NewComposite creates a Composite, weights must be nonnegative and match the fields
+func NewComposite(weights []Distance, fields ...MetricTensor) (Composite, error) {
+	if len(weights) != len(fields) {
+		return Composite{}, errors.New("go_bk_tree: composite needs one weight per field")
+	}
+	for _, w := range weights {
+		if w < 0 {
+			return Composite{}, errors.New("go_bk_tree: composite weights must be nonnegative")
+		}
+	}
+	return Composite{Fields: fields, Weights: weights}, nil
+}
+
+func (c Composite) DistanceFrom(other MetricTensor) Distance {
+	o := other.(Composite)
+	var dist Distance
+	for i, field := range c.Fields {
+		dist = addDistance(dist, c.Weights[i]*field.DistanceFrom(o.Fields[i]))
+	}
+	return dist
+}
+
+func (c Composite) ToString() string {
+	strs := make([]string, len(c.Fields))
+	for i, field := range c.Fields {
+		strs[i] = field.ToString()
+	}
+	return strings.Join(strs, "|")
+}
@@ -0,0 +1,36 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComposite(t *testing.T) {
+	if _, err := NewComposite([]Distance{1, -1}, Word("a"), Word("b")); err == nil {
+		t.Error("expected an error for a negative weight")
+	}
+	if _, err := NewComposite([]Distance{1}, Word("a"), Word("b")); err == nil {
+		t.Error("expected an error for missing weights")
+	}
+	record := func(name, category string) Composite {
+		c, _ := NewComposite([]Distance{1, 10}, Word(name), Word(category))
+		return c
+	}
+	if d := record("apple", "fruit").DistanceFrom(record("appel", "fruit")); d != 2 {
+		t.Errorf("expected: %d, got: %d", 2, d)
+	}
+	if d := record("apple", "fruit").DistanceFrom(record("apple", "fruits")); d != 10 {
+		t.Errorf("expected: %d, got: %d", 10, d)
+	}
+	tree := new(BKTree)
+	for _, r := range []Composite{record("apple", "fruit"), record("apple", "brand"), record("appel", "fruit"), record("pear", "fruit")} {
+		tree.Add(r)
+	}
+	results, _ := tree.Search(record("apple", "fruit"), 3)
+	if expected := []string{"appel|fruit", "apple|fruit"}; !reflect.DeepEqual(sortedStrings(results), expected) {
+		t.Errorf("expected: %v, got: %v", expected, sortedStrings(results))
+	}
+	if record("apple", "fruit").ToString() != "apple|fruit" {
+		t.Errorf("unexpected ToString: %s", record("apple", "fruit").ToString())
+	}
+}
@@ -0,0 +1,45 @@
+package go_bk_tree
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// CompressOptions configures WriteCompressedWithOptions
+type CompressOptions struct {
+	// Level is a compress/gzip level from gzip.BestSpeed to gzip.BestCompression (or
+	// gzip.HuffmanOnly), 0 means gzip.DefaultCompression
+	Level int
+}
+
+// WriteCompressed writes the tree to w in the ToJson form compressed with gzip, trees
+// of strings typically shrink several times. Read it back with ReadCompressed.
+func (tree *BKTree) WriteCompressed(w io.Writer) error {
+	return tree.WriteCompressedWithOptions(w, CompressOptions{})
+}
+
+// WriteCompressedWithOptions works like WriteCompressed, see CompressOptions
+func (tree *BKTree) WriteCompressedWithOptions(w io.Writer, opts CompressOptions) error {
+	level := opts.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return err
+	}
+	if err := tree.WriteSnapshot(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadCompressed rebuilds a tree written by WriteCompressed, see FromJson
+func ReadCompressed(r io.Reader, factory func(string) MetricTensor) (*BKTree, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return LoadWithChanges(gz, nil, factory)
+}
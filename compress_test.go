@@ -0,0 +1,34 @@
+package go_bk_tree
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestBKTree_WriteCompressed(t *testing.T) {
+	tree, _ := BuildFromSlice(makeRandomWords(2000, 5), nil)
+	plain, _ := tree.ToJson()
+	for _, level := range []int{0, gzip.BestSpeed, gzip.BestCompression} {
+		var buf bytes.Buffer
+		if err := tree.WriteCompressedWithOptions(&buf, CompressOptions{Level: level}); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() >= len(plain) {
+			t.Errorf("level %d: expected less than %d bytes, got: %d", level, len(plain), buf.Len())
+		}
+		decoded, err := ReadCompressed(&buf, wordFactory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EqualStructure(tree, decoded) {
+			t.Errorf("level %d: expected the round-trip to preserve the tree", level)
+		}
+	}
+	if err := tree.WriteCompressedWithOptions(new(bytes.Buffer), CompressOptions{Level: 42}); err == nil {
+		t.Error("expected an invalid level to be rejected")
+	}
+	if _, err := ReadCompressed(bytes.NewReader(plain), wordFactory); err == nil {
+		t.Error("expected uncompressed input to be rejected")
+	}
+}
@@ -0,0 +1,48 @@
+package go_bk_tree
+
+import "math/rand"
+
+// Dataset describes random strings to benchmark and test trees reproducibly, e.g. to
+// check in CI that a metric keeps pruning as well as before. The strings only depend
+// on the Dataset and their number, math/rand guaranteeing the sequence of a seed.
+type Dataset struct {
+	// Alphabet holds the symbols the strings are made of, the lower case ASCII letters
+	// when empty. A small alphabet makes the strings closer to each other.
+	Alphabet string
+	// MinLen and MaxLen bound the length of the strings, which is uniformly distributed,
+	// a MaxLen below MinLen makes every string MinLen long
+	MinLen, MaxLen int
+	Seed           int64
+}
+
+// Strings returns n random strings, which may contain duplicates
+func (d Dataset) Strings(n int) []string {
+	alphabet := []rune(d.Alphabet)
+	if len(alphabet) == 0 {
+		alphabet = []rune("abcdefghijklmnopqrstuvwxyz")
+	}
+	r := rand.New(rand.NewSource(d.Seed))
+	strs := make([]string, n)
+	for i := range strs {
+		length := d.MinLen
+		if d.MaxLen > d.MinLen {
+			length += r.Intn(d.MaxLen - d.MinLen + 1)
+		}
+		s := make([]rune, length)
+		for j := range s {
+			s[j] = alphabet[r.Intn(len(alphabet))]
+		}
+		strs[i] = string(s)
+	}
+	return strs
+}
+
+// Tree returns a tree of the n strings of Strings added in order, each as
+// factory(s), so that its Size is n minus the duplicates
+func (d Dataset) Tree(n int, factory func(string) MetricTensor) *BKTree {
+	tree := new(BKTree)
+	for _, s := range d.Strings(n) {
+		tree.Add(factory(s))
+	}
+	return tree
+}
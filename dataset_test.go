@@ -0,0 +1,39 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDataset(t *testing.T) {
+	d := Dataset{Alphabet: "acgt", MinLen: 4, MaxLen: 9, Seed: 7}
+	strs := d.Strings(1000)
+	if !reflect.DeepEqual(strs, d.Strings(1000)) {
+		t.Error("expected the same strings for the same dataset")
+	}
+	if reflect.DeepEqual(strs, Dataset{Alphabet: "acgt", MinLen: 4, MaxLen: 9, Seed: 8}.Strings(1000)) {
+		t.Error("expected other strings for another seed")
+	}
+	lengths := make(map[int]bool)
+	for _, s := range strs {
+		if len(s) < 4 || len(s) > 9 || strings.Trim(s, "acgt") != "" {
+			t.Fatalf("unexpected string: %q", s)
+		}
+		lengths[len(s)] = true
+	}
+	if len(lengths) != 6 {
+		t.Errorf("expected every length from 4 to 9, got: %v", lengths)
+	}
+	if s := (Dataset{MinLen: 3}).Strings(1)[0]; len(s) != 3 || strings.Trim(s, "abcdefghijklmnopqrstuvwxyz") != "" {
+		t.Errorf("unexpected default string: %q", s)
+	}
+	tree := d.Tree(1000, func(s string) MetricTensor { return Word(s) })
+	distinct := make(map[string]bool)
+	for _, s := range strs {
+		distinct[s] = true
+	}
+	if tree.Size != len(distinct) {
+		t.Errorf("expected: %d, got: %d", len(distinct), tree.Size)
+	}
+}
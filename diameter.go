@@ -0,0 +1,36 @@
+package go_bk_tree
+
+// DiameterEstimate approximates the largest distance between two values, e.g. as a
+// starting point to choose search radii, with a double sweep: a is the value farthest
+// from the root, and the estimate is the distance between a and the value farthest
+// from it. It is an estimate, not the exact diameter, which takes n^2 distances: the
+// diameter lies between the estimate and twice the estimate (by the triangle
+// inequality through a), and it is exact for values on a line (e.g. numbers with the
+// absolute difference). It costs 2n distance computations, and returns 0 for fewer
+// than two values.
+func (tree *BKTree) DiameterEstimate() Distance {
+	if tree.Root == nil {
+		return 0
+	}
+	a, _ := tree.farthestFrom(tree.Root.MetricTensor)
+	if a == nil {
+		return 0
+	}
+	_, d := tree.farthestFrom(a)
+	return d
+}
+
+// farthestFrom returns the value farthest from val, nil values being skipped
+func (tree *BKTree) farthestFrom(val MetricTensor) (MetricTensor, Distance) {
+	var farthest MetricTensor
+	best := Distance(-1)
+	for v := range tree.All() {
+		if v == nil {
+			continue
+		}
+		if d := v.DistanceFrom(val); d > best {
+			farthest, best = v, d
+		}
+	}
+	return farthest, max(best, 0)
+}
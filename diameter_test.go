@@ -0,0 +1,31 @@
+package go_bk_tree
+
+import "testing"
+
+func TestBKTree_DiameterEstimate(t *testing.T) {
+	numbers := []bigNumber{50, 40, 60, 10, 90, 55}
+	tree := new(BKTree)
+	for _, n := range numbers {
+		tree.Add(n)
+	}
+	if d := tree.DiameterEstimate(); d != 80 {
+		t.Errorf("expected: %d, got: %d", 80, d)
+	}
+
+	hashes, hamming := makeRandomHammingTree(300, 2)
+	var exact Distance
+	for i, a := range hashes {
+		for _, b := range hashes[i+1:] {
+			exact = max(exact, a.DistanceFrom(b))
+		}
+	}
+	if d := hamming.DiameterEstimate(); d > exact || 2*d < exact {
+		t.Errorf("expected an estimate between %d and %d, got: %d", (exact+1)/2, exact, d)
+	}
+	if d := createNewTreeFromWords([]string{"some"}).DiameterEstimate(); d != 0 {
+		t.Errorf("expected: %d, got: %d", 0, d)
+	}
+	if d := new(BKTree).DiameterEstimate(); d != 0 {
+		t.Errorf("expected: %d, got: %d", 0, d)
+	}
+}
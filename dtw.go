@@ -0,0 +1,50 @@
+package go_bk_tree
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SequenceScale is the factor the DTW cost of two Sequences is multiplied by
+// before being rounded to a Distance
+const SequenceScale = 1000
+
+// Sequence is a built-in MetricTensor for short numeric time series, the distance
+// between two sequences is their Dynamic Time Warping cost (sum of absolute
+// differences along the optimal warping path) scaled by SequenceScale.
+//
+// Notice: DTW does not satisfy the triangle inequality, so the pruning done by
+// Search may miss some matches. Use SearchExhaustive when every match matters.
+type Sequence []float64
+
+func (s Sequence) DistanceFrom(other MetricTensor) Distance {
+	o := other.(Sequence)
+	if len(s) == 0 || len(o) == 0 {
+		if len(s) == len(o) {
+			return 0
+		}
+		return math.MaxInt32
+	}
+	prev := make([]float64, len(o)+1)
+	cur := make([]float64, len(o)+1)
+	for j := 1; j <= len(o); j++ {
+		prev[j] = math.Inf(1)
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = math.Inf(1)
+		for j := 1; j <= len(o); j++ {
+			cur[j] = math.Abs(s[i-1]-o[j-1]) + math.Min(prev[j-1], math.Min(prev[j], cur[j-1]))
+		}
+		prev, cur = cur, prev
+	}
+	return DistanceFromFloat(prev[len(o)] * SequenceScale)
+}
+
+func (s Sequence) ToString() string {
+	strs := make([]string, len(s))
+	for i, f := range s {
+		strs[i] = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strings.Join(strs, ",")
+}
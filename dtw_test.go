@@ -0,0 +1,57 @@
+package go_bk_tree
+
+import "testing"
+
+func TestSequence_DistanceFrom(t *testing.T) {
+	a := Sequence{1, 2, 3, 4}
+	if d := a.DistanceFrom(Sequence{1, 2, 3, 4}); d != 0 {
+		t.Errorf("expected: %d, got: %d", 0, d)
+	}
+	// a time-shifted copy only pays for the warping at the edges
+	if d := a.DistanceFrom(Sequence{1, 1, 2, 3, 4}); d != 0 {
+		t.Errorf("expected: %d, got: %d", 0, d)
+	}
+	if d := a.DistanceFrom(Sequence{1, 2, 3, 4.5}); d != SequenceScale/2 {
+		t.Errorf("expected: %d, got: %d", SequenceScale/2, d)
+	}
+}
+
+func TestBKTree_SearchExhaustive(t *testing.T) {
+	tree := new(BKTree)
+	seqs := []Sequence{{0, 0, 0}, {1, 2, 3}, {1, 2, 3, 6}, {5, 5, 5}, {1, 2, 4}}
+	for _, s := range seqs {
+		tree.Add(s)
+	}
+	results, count := tree.SearchExhaustive(Sequence{1, 2, 3}, SequenceScale)
+	if count != tree.Size {
+		t.Errorf("expected: %d, got: %d", tree.Size, count)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected: %d, got: %d (%v)", 2, len(results), results)
+	}
+}
+
+func TestBKTree_Epsilon(t *testing.T) {
+	noisy := []Sequence{{1, 2, 3}, {1, 2, 3.0011}, {1.0009, 2, 3}, {1, 2, 2.9988}, {4, 5, 6}}
+	exact := new(BKTree)
+	for _, s := range noisy {
+		exact.Add(s)
+	}
+	if exact.Size != len(noisy) {
+		t.Errorf("expected: %d, got: %d", len(noisy), exact.Size)
+	}
+
+	tree := &BKTree{Epsilon: 2}
+	for _, s := range noisy {
+		tree.Add(s)
+	}
+	if tree.Size != 2 {
+		t.Errorf("expected: %d, got: %d", 2, tree.Size)
+	}
+	if tree.Find(Sequence{1, 2, 3.0015}) == nil {
+		t.Error("expected a value within epsilon to be found")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
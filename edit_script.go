@@ -0,0 +1,105 @@
+package go_bk_tree
+
+import "fmt"
+
+// EditOpKind is the kind of an EditOp
+type EditOpKind byte
+
+const (
+	EditInsert     EditOpKind = 'i'
+	EditDelete     EditOpKind = 'd'
+	EditSubstitute EditOpKind = 's'
+)
+
+// EditOp is one operation of an edit script turning a source string into a target.
+// SourcePos and TargetPos are rune offsets: a deletion removes the rune From at
+// SourcePos of the source, an insertion adds the rune To at TargetPos of the target
+// (before the rune at SourcePos of the source), and a substitution replaces From at
+// SourcePos with To at TargetPos. The runes of the unchanged positions are not listed.
+type EditOp struct {
+	Kind                 EditOpKind
+	SourcePos, TargetPos int
+	From, To             rune
+}
+
+// String formats the operation as "d 3 'x'" (delete x at 3), "i 3 'x'" (insert x at
+// target position 3) or "s 3 'x'->'y'"
+func (op EditOp) String() string {
+	switch op.Kind {
+	case EditDelete:
+		return fmt.Sprintf("d %d %q", op.SourcePos, op.From)
+	case EditInsert:
+		return fmt.Sprintf("i %d %q", op.TargetPos, op.To)
+	}
+	return fmt.Sprintf("s %d %q->%q", op.SourcePos, op.From, op.To)
+}
+
+// EditScript returns the Levenshtein distance between s and other with a shortest
+// edit script turning s into other, in increasing position order, so that its length
+// is the distance. When several scripts are as short, substitutions are preferred to
+// deletions and deletions to insertions. It costs a full len(s)*len(other) matrix,
+// more than DistanceFrom which keeps a single row, so only use it to explain results.
+func (s EditString) EditScript(other EditString) (Distance, []EditOp) {
+	src, dst := []rune(string(s)), []rune(string(other))
+	cols := len(dst) + 1
+	m := make([]int, (len(src)+1)*cols)
+	for i := 0; i <= len(src); i++ {
+		m[i*cols] = i
+	}
+	for j := 0; j <= len(dst); j++ {
+		m[j] = j
+	}
+	for i := 1; i <= len(src); i++ {
+		for j := 1; j <= len(dst); j++ {
+			sub := m[(i-1)*cols+j-1]
+			if src[i-1] != dst[j-1] {
+				sub++
+			}
+			m[i*cols+j] = min(sub, m[(i-1)*cols+j]+1, m[i*cols+j-1]+1)
+		}
+	}
+	dist := m[len(src)*cols+len(dst)]
+	script := make([]EditOp, dist)
+	n := dist
+	for i, j := len(src), len(dst); i > 0 || j > 0; {
+		cur := m[i*cols+j]
+		switch {
+		case i > 0 && j > 0 && src[i-1] == dst[j-1] && cur == m[(i-1)*cols+j-1]:
+			i, j = i-1, j-1
+			continue
+		case i > 0 && j > 0 && cur == m[(i-1)*cols+j-1]+1:
+			i, j = i-1, j-1
+			script[n-1] = EditOp{EditSubstitute, i, j, src[i], dst[j]}
+		case i > 0 && cur == m[(i-1)*cols+j]+1:
+			i--
+			script[n-1] = EditOp{Kind: EditDelete, SourcePos: i, TargetPos: j, From: src[i]}
+		default:
+			j--
+			script[n-1] = EditOp{Kind: EditInsert, SourcePos: i, TargetPos: j, To: dst[j]}
+		}
+		n--
+	}
+	return Distance(dist), script
+}
+
+// EditMatch is a value found by SearchEditScript with the edit script turning the
+// query into it, see EditString.EditScript
+type EditMatch struct {
+	Value    MetricTensor
+	Distance Distance
+	Script   []EditOp
+}
+
+// SearchEditScript works like Search on a tree of EditStrings and returns every match
+// with the edit script turning query into it, e.g. to highlight the differences of
+// "did you mean" suggestions. Matches are in the order of Search, the scripts being
+// computed for the matches only.
+func (tree *BKTree) SearchEditScript(query EditString, radius Distance) ([]EditMatch, int) {
+	results, count := tree.Search(query, radius)
+	matches := make([]EditMatch, len(results))
+	for i, r := range results {
+		dist, script := query.EditScript(r.(EditString))
+		matches[i] = EditMatch{r, dist, script}
+	}
+	return matches, count
+}
@@ -0,0 +1,45 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditString_EditScript(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected []string
+	}{
+		{"kitten", "sitting", []string{"s 0 'k'->'s'", "s 4 'e'->'i'", "i 6 'g'"}},
+		{"flaw", "lawn", []string{"d 0 'f'", "i 3 'n'"}},
+		{"abc", "abc", []string{}},
+		{"", "ab", []string{"i 0 'a'", "i 1 'b'"}},
+		{"héllo", "hello", []string{"s 1 'é'->'e'"}},
+	}
+	for _, c := range cases {
+		dist, script := EditString(c.a).EditScript(EditString(c.b))
+		got := make([]string, len(script))
+		for i, op := range script {
+			got[i] = op.String()
+		}
+		if !reflect.DeepEqual(got, c.expected) || dist != EditString(c.a).DistanceFrom(EditString(c.b)) {
+			t.Errorf("%s -> %s: expected: %v, got: %d %v", c.a, c.b, c.expected, dist, got)
+		}
+	}
+}
+
+func TestBKTree_SearchEditScript(t *testing.T) {
+	tree := new(BKTree)
+	for _, w := range []string{"hello", "help", "world", "hallo"} {
+		tree.Add(EditString(w))
+	}
+	matches, _ := tree.SearchEditScript("helo", 1)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got: %v", matches)
+	}
+	for _, m := range matches {
+		if len(m.Script) != int(m.Distance) || m.Distance != 1 {
+			t.Errorf("%v: expected a script of 1 operation, got: %v", m.Value, m.Script)
+		}
+	}
+}
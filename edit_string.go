@@ -0,0 +1,118 @@
+package go_bk_tree
+
+import "sort"
+
+// EditString is a built-in MetricTensor for strings, the distance is the Levenshtein
+// distance (unit costs) computed over runes
+type EditString string
+
+func (s EditString) DistanceFrom(other MetricTensor) Distance {
+	var p prefixDistance
+	p.reset([]rune(string(other.(EditString))))
+	return Distance(p.distance([]rune(string(s))))
+}
+
+func (s EditString) ToString() string {
+	return string(s)
+}
+
+// prefixDistance computes the edit distance between a fixed query and a sequence of
+// candidates, reusing the rows of the dynamic programming matrix shared with the
+// previous candidate: the first i rows only depend on the first i runes of the candidate.
+type prefixDistance struct {
+	query []rune
+	prev  []rune
+	rows  [][]int // rows[i] is the row after the first i runes of prev
+}
+
+func (p *prefixDistance) reset(query []rune) {
+	p.query, p.prev = query, p.prev[:0]
+	first := make([]int, len(query)+1)
+	for j := range first {
+		first[j] = j
+	}
+	p.rows = append(p.rows[:0], first)
+}
+
+func (p *prefixDistance) distance(cand []rune) int {
+	lcp := 0
+	for lcp < len(cand) && lcp < len(p.prev) && cand[lcp] == p.prev[lcp] {
+		lcp++
+	}
+	for i := lcp; i < len(cand); i++ {
+		var row []int
+		if i+1 < len(p.rows) {
+			row = p.rows[i+1]
+		} else {
+			row = make([]int, len(p.query)+1)
+			p.rows = append(p.rows, row)
+		}
+		prevRow := p.rows[i]
+		row[0] = i + 1
+		for j := 1; j <= len(p.query); j++ {
+			sub := prevRow[j-1]
+			if cand[i] != p.query[j-1] {
+				sub++
+			}
+			row[j] = min(sub, prevRow[j]+1, row[j-1]+1)
+		}
+	}
+	p.prev = append(p.prev[:0], cand...)
+	return p.rows[len(cand)][len(p.query)]
+}
+
+// SearchEditString works like Search on a tree of EditStrings but shares the edit
+// distance computation between candidates: each level of candidates is sorted so that
+// consecutive ones share long prefixes, and the distance matrix rows of a common prefix
+// are only computed once. Results are the same as Search's, possibly in another order,
+// and MaxResults stops the search at the first matches found in that order.
+func (tree *BKTree) SearchEditString(query EditString, radius Distance) ([]MetricTensor, int) {
+	count := 0
+	results := make([]MetricTensor, 0, 5)
+	if tree.Root == nil {
+		return results, count
+	}
+	var p prefixDistance
+	p.reset([]rune(string(query)))
+	type candidate struct {
+		node  *BkTreeNode
+		runes []rune
+	}
+	// push appends node to level, or the children of a nil value which gives no bound
+	var push func(level []candidate, node *BkTreeNode) []candidate
+	push = func(level []candidate, node *BkTreeNode) []candidate {
+		if node.MetricTensor == nil {
+			for _, child := range node.Children {
+				level = push(level, child)
+			}
+			return level
+		}
+		return append(level, candidate{node, []rune(string(node.MetricTensor.(EditString)))})
+	}
+	level := push(nil, tree.Root)
+	var next []candidate
+	for len(level) > 0 && !tree.capped(len(results)) {
+		sort.Slice(level, func(i, j int) bool {
+			return string(level[i].node.MetricTensor.(EditString)) < string(level[j].node.MetricTensor.(EditString))
+		})
+		next = next[:0]
+		for _, cand := range level {
+			dist := Distance(p.distance(cand.runes))
+			count += 1
+			if dist <= radius {
+				results = append(results, cand.node.MetricTensor)
+				if tree.capped(len(results)) {
+					break
+				}
+			}
+			low, high := searchWindow(dist, radius)
+			for d, child := range cand.node.Children {
+				if d >= low && d <= high {
+					next = push(next, child)
+				}
+			}
+		}
+		level, next = next, level
+	}
+	return results, count
+}
@@ -0,0 +1,133 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestEditString_DistanceFrom(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected Distance
+	}{
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"flaw", "lawn", 2},
+		{"héllo", "hello", 1},
+	}
+	for _, c := range cases {
+		if d := EditString(c.a).DistanceFrom(EditString(c.b)); d != c.expected {
+			t.Errorf("%s -> %s: expected: %d, got: %d", c.a, c.b, c.expected, d)
+		}
+	}
+	var p prefixDistance
+	p.reset([]rune("sitting"))
+	for _, w := range []string{"kitten", "kitchen", "kit", "sitting", "sit"} {
+		if d := Distance(p.distance([]rune(w))); d != EditString(w).DistanceFrom(EditString("sitting")) {
+			t.Errorf("%s: expected: %d, got: %d", w, EditString(w).DistanceFrom(EditString("sitting")), d)
+		}
+	}
+}
+
+func makeEditStringTree(size int, seed int64) ([]MetricTensor, *BKTree) {
+	words := makeRandomWords(size, seed)
+	tree := new(BKTree)
+	for i, w := range words {
+		words[i] = EditString(w.(Word))
+		tree.Add(words[i])
+	}
+	return words, tree
+}
+
+func TestBKTree_SearchEditString(t *testing.T) {
+	words, tree := makeEditStringTree(3000, 13)
+	for _, q := range words[:20] {
+		expected, expectedCount := tree.Search(q, 2)
+		got, count := tree.SearchEditString(q.(EditString), 2)
+		if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || count != expectedCount {
+			t.Errorf("%s: expected: %v, got: %v", q, sortedStrings(expected), sortedStrings(got))
+		}
+	}
+}
+
+func TestBKTree_SearchEditString_MaxResults(t *testing.T) {
+	words, tree := makeEditStringTree(3000, 14)
+	all, _ := tree.Search(words[0], 4)
+	if len(all) <= 5 {
+		t.Fatalf("expected more than 5 matches, got: %d", len(all))
+	}
+	tree.MaxResults = 5
+	got, _ := tree.SearchEditString(words[0].(EditString), 4)
+	streamed, _ := tree.NewStreamingSearch().Search(words[0], 4)
+	for name, results := range map[string][]MetricTensor{"SearchEditString": got, "StreamingSearch": streamed} {
+		if len(results) != 5 {
+			t.Errorf("%s: expected: %d, got: %d", name, 5, len(results))
+		}
+		for _, r := range results {
+			if !slices.Contains(all, r) {
+				t.Errorf("%s: expected a match, got: %v", name, r)
+			}
+		}
+	}
+}
+
+func BenchmarkBKTree_Search_EditString(b *testing.B) {
+	words, tree := makeEditStringTree(20000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(words[i%len(words)], 2)
+	}
+}
+
+func BenchmarkBKTree_SearchEditString(b *testing.B) {
+	words, tree := makeEditStringTree(20000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.SearchEditString(words[i%len(words)].(EditString), 2)
+	}
+}
+
+func TestStreamingSearch(t *testing.T) {
+	tree := new(BKTree)
+	for _, w := range []string{"cat", "car", "cart", "care", "dog", "cattle", "scar", "catalog"} {
+		tree.Add(EditString(w))
+	}
+	s := tree.NewStreamingSearch()
+	for _, q := range []string{"c", "ca", "car", "cart", "ca", "catal"} {
+		expected, expectedCount := tree.Search(EditString(q), 1)
+		got, count := s.Search(EditString(q), 1)
+		if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || count != expectedCount {
+			t.Errorf("%s: expected: %v, got: %v", q, sortedStrings(expected), sortedStrings(got))
+		}
+	}
+	for _, w := range []string{"", "a", "kitten", "sitting"} {
+		for _, o := range []string{"", "kitten", "sitting", "mitt"} {
+			if d, _ := EditString(w).ResumeDistance(EditString(o), nil); d != EditString(w).DistanceFrom(EditString(o)) {
+				t.Errorf("%q-%q: expected: %d, got: %d", w, o, EditString(w).DistanceFrom(EditString(o)), d)
+			}
+		}
+	}
+}
+
+func TestWarmSearch(t *testing.T) {
+	tree := Dataset{Alphabet: "abcdef", MinLen: 4, MaxLen: 8, Seed: 3}.Tree(3000, func(s string) MetricTensor { return EditString(s) })
+	ws := tree.NewWarmSearch(2)
+	for i, q := range []string{"abcde", "abcdf", "abdf", "abcdef", "fedcba", "fedcb"} {
+		expected, fullCount := tree.Search(EditString(q), 1)
+		got, count := ws.Search(EditString(q), 1)
+		if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) {
+			t.Errorf("%s: expected: %v, got: %v", q, sortedStrings(expected), sortedStrings(got))
+		}
+		// the edits of the previous query are answered from the kept matches
+		if warm := i != 0 && i != 4; warm && count >= fullCount {
+			t.Errorf("%s: expected fewer than %d distance computations, got: %d", q, fullCount, count)
+		}
+	}
+	tree.Add(EditString("fedcbb"))
+	if got, _ := ws.Search(EditString("fedcb"), 1); !slices.Contains(got, MetricTensor(EditString("fedcbb"))) {
+		t.Errorf("expected a value added since the last search to be found, got: %v", got)
+	}
+}
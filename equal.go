@@ -0,0 +1,45 @@
+package go_bk_tree
+
+// Equal reports whether a and b hold the same values regardless of where they are
+// stored, values being compared by their ToString (as serialization does)
+func Equal(a, b *BKTree) bool {
+	counts := make(map[string]int)
+	for v := range a.All() {
+		counts[v.ToString()]++
+	}
+	for v := range b.All() {
+		s := v.ToString()
+		if counts[s] == 0 {
+			return false
+		}
+		counts[s]--
+	}
+	for _, c := range counts {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualStructure reports whether a and b have the same shape: the same value (by
+// ToString) at the root and, for every bucket, structurally equal children. Map
+// iteration order plays no role, so a tree equals its JSON round-trip.
+func EqualStructure(a, b *BKTree) bool {
+	return equalNodes(a.Root, b.Root)
+}
+
+func equalNodes(a, b *BkTreeNode) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.ToString() != b.ToString() || len(a.Children) != len(b.Children) {
+		return false
+	}
+	for dist, child := range a.Children {
+		if !equalNodes(child, b.Children[dist]) {
+			return false
+		}
+	}
+	return true
+}
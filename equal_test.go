@@ -0,0 +1,26 @@
+package go_bk_tree
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	words := []string{"some", "soft", "same", "mole", "soda"}
+	tree := createNewTreeFromWords(words)
+	reordered := createNewTreeFromWords([]string{"soda", "mole", "same", "soft", "some"})
+	data, _ := tree.ToJson()
+	decoded, err := FromJson(data, wordFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(tree, reordered) || !Equal(tree, decoded) {
+		t.Error("expected the trees to hold the same values")
+	}
+	if EqualStructure(tree, reordered) || !EqualStructure(tree, decoded) {
+		t.Error("expected only the round-trip to have the same structure")
+	}
+	if smaller := createNewTreeFromWords(words[:4]); Equal(tree, smaller) || EqualStructure(tree, smaller) {
+		t.Error("expected trees of different sizes to differ")
+	}
+	if !Equal(new(BKTree), new(BKTree)) || !EqualStructure(new(BKTree), new(BKTree)) {
+		t.Error("expected empty trees to be equal")
+	}
+}
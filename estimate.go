@@ -0,0 +1,47 @@
+package go_bk_tree
+
+import "math/rand"
+
+// estimateProbes is the number of random descents EstimateSearchCost averages
+const estimateProbes = 32
+
+// EstimateSearchCost approximates the number of distance computations of
+// Search(val, radius) without running it, e.g. to reject overly broad queries or to
+// pick SearchApprox instead. It is a heuristic based on a sample of the tree's shape:
+// Knuth's estimator follows random paths from the root through the children Search
+// would explore, and multiplies the number of such children met at each level. The
+// average over estimateProbes paths is unbiased but noisy, single estimates can be
+// off by a factor of two or more on unbalanced trees, so compare it to thresholds far
+// apart rather than to the exact cost. It costs about estimateProbes times the height
+// of the tree in distance computations.
+func (tree *BKTree) EstimateSearchCost(val MetricTensor, radius Distance) int {
+	if tree.Root == nil || val == nil {
+		return 0
+	}
+	val = tree.normalize(val)
+	total := 0.0
+	var explored []*BkTreeNode
+	for p := 0; p < estimateProbes; p++ {
+		node, weight := tree.Root, 1.0
+		for node != nil {
+			explored = explored[:0]
+			if node.MetricTensor == nil {
+				explored = appendChildren(explored, node)
+			} else {
+				total += weight
+				low, high := searchWindow(node.DistanceFrom(val), radius)
+				for d, child := range node.Children {
+					if d >= low && d <= high {
+						explored = append(explored, child)
+					}
+				}
+			}
+			if len(explored) == 0 {
+				break
+			}
+			weight *= float64(len(explored))
+			node = explored[rand.Intn(len(explored))]
+		}
+	}
+	return int(total/estimateProbes + 0.5)
+}
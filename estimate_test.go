@@ -0,0 +1,21 @@
+package go_bk_tree
+
+import "testing"
+
+func TestBKTree_EstimateSearchCost(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(5000, 61)
+	for _, radius := range []Distance{4, 12, 20} {
+		estimated, actual := 0, 0
+		for _, q := range hashes[:50] {
+			estimated += tree.EstimateSearchCost(q^0x5, radius)
+			_, count := tree.Search(q^0x5, radius)
+			actual += count
+		}
+		if ratio := float64(estimated) / float64(actual); ratio < 0.6 || ratio > 1.6 {
+			t.Errorf("radius %d: expected about %d distances, estimated %d", radius, actual, estimated)
+		}
+	}
+	if cost := new(BKTree).EstimateSearchCost(Hamming64(0), 4); cost != 0 {
+		t.Errorf("expected no cost on an empty tree, got %d", cost)
+	}
+}
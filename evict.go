@@ -0,0 +1,76 @@
+package go_bk_tree
+
+import "time"
+
+// EvictOlderThan removes the nodes added more than d ago and returns how many were
+// evicted, nodes without a timestamp are never evicted (see BKTree.Timestamps). Size
+// is updated accordingly. The values below an evicted node lose their position
+// and are reinserted one by one (keeping their timestamps), each reinsertion costing
+// a descent from the root, so evicting near the root is much more expensive than
+// evicting leaves.
+func (tree *BKTree) EvictOlderThan(d time.Duration) int {
+	if tree.Root == nil {
+		return 0
+	}
+	cutoff := time.Now().Add(-d)
+	return tree.removeWhere(func(node *BkTreeNode) bool {
+		return !node.AddedAt.IsZero() && node.AddedAt.Before(cutoff)
+	})
+}
+
+// removeWhere unlinks the nodes for which remove returns true and reinserts the other
+// values of their subtrees, it returns the number of removed nodes
+func (tree *BKTree) removeWhere(remove func(node *BkTreeNode) bool) int {
+	removed := 0
+	var orphans []*BkTreeNode
+	collect := func(node *BkTreeNode) {
+		for _, n := range collectNodes(node, nil) {
+			if remove(n) {
+				removed++
+			} else {
+				orphans = append(orphans, n)
+			}
+		}
+	}
+	// walk reports whether the subtree of node changed, which drops its frozen copy
+	var walk func(node *BkTreeNode) bool
+	walk = func(node *BkTreeNode) bool {
+		changed := false
+		for dist, child := range node.Children {
+			if remove(child) {
+				delete(node.Children, dist)
+				collect(child)
+				changed = true
+			} else if walk(child) {
+				changed = true
+			}
+		}
+		if changed {
+			node.frozen = nil
+		}
+		return changed
+	}
+	if remove(tree.Root) {
+		collect(tree.Root)
+		tree.Root, tree.sizedRoot = nil, nil
+	} else {
+		walk(tree.Root)
+	}
+	tree.Size -= removed + len(orphans)
+	if removed > 0 {
+		tree.version++
+	}
+	tree.reinsert(orphans)
+	return removed
+}
+
+// evictOldest removes the node with the oldest AddedAt, nodes without a timestamp first
+func (tree *BKTree) evictOldest() {
+	oldest := tree.Root
+	for _, node := range collectNodes(tree.Root, nil) {
+		if node.AddedAt.Before(oldest.AddedAt) {
+			oldest = node
+		}
+	}
+	tree.RemoveNode(oldest)
+}
@@ -0,0 +1,80 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBKTree_EvictOlderThan(t *testing.T) {
+	tree := &BKTree{Timestamps: true}
+	wordsList := []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"}
+	for _, w := range wordsList {
+		tree.Add(Word(w))
+	}
+	old := time.Now().Add(-time.Hour)
+	for _, w := range []string{"some", "soda"} {
+		tree.Find(Word(w)).AddedAt = old
+	}
+	stamp := tree.Find(Word("salmon")).AddedAt
+	if stamp.IsZero() {
+		t.Fatal("expected nodes to be stamped")
+	}
+	if evicted := tree.EvictOlderThan(time.Minute); evicted != 2 {
+		t.Errorf("expected: %d, got: %d", 2, evicted)
+	}
+	if tree.Size != 5 {
+		t.Errorf("expected: %d, got: %d", 5, tree.Size)
+	}
+	var got []MetricTensor
+	for v := range tree.All() {
+		got = append(got, v)
+	}
+	if expected := []string{"mole", "salmon", "same", "soft", "sorted"}; !reflect.DeepEqual(sortedStrings(got), expected) {
+		t.Errorf("expected: %v, got: %v", expected, sortedStrings(got))
+	}
+	if !tree.Find(Word("salmon")).AddedAt.Equal(stamp) {
+		t.Error("expected reinserted nodes to keep their timestamp")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+	if evicted := createNewTreeFromWords(wordsList).EvictOlderThan(0); evicted != 0 {
+		t.Errorf("expected nodes without timestamps to be kept, got %d evicted", evicted)
+	}
+}
+
+func TestBKTree_MaxSize(t *testing.T) {
+	full := &BKTree{MaxSize: 2}
+	for _, w := range []string{"some", "soft", "some"} {
+		if err := full.Add(Word(w)); err != nil {
+			t.Fatalf("unexpected error adding %s: %v", w, err)
+		}
+	}
+	if err := full.Add(Word("mole")); !errors.Is(err, ErrTreeFull) || full.Size != 2 {
+		t.Errorf("expected: %v with size 2, got: %v with size %d", ErrTreeFull, err, full.Size)
+	}
+
+	tree := &BKTree{MaxSize: 3, Overflow: EvictOldest, Timestamps: true}
+	start := time.Now().Add(-time.Hour)
+	for i, w := range []string{"some", "soft", "sorted"} {
+		tree.Add(Word(w))
+		tree.Find(Word(w)).AddedAt = start.Add(time.Duration(i) * time.Minute)
+	}
+	for _, w := range []string{"mole", "soda"} {
+		if err := tree.Add(Word(w)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []MetricTensor
+	for v := range tree.All() {
+		got = append(got, v)
+	}
+	if expected := []string{"mole", "soda", "sorted"}; !reflect.DeepEqual(sortedStrings(got), expected) || tree.Size != 3 {
+		t.Errorf("expected: %v, got: %v with size %d", expected, sortedStrings(got), tree.Size)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,54 @@
+package go_bk_tree
+
+// ExplainStep records what Search did at one visited node
+type ExplainStep struct {
+	Value    MetricTensor
+	Distance Distance // distance between the query and Value
+	Match    bool     // Distance <= radius
+	// Low and High bound the child buckets that may hold matches
+	Low, High Distance
+	Explored  []Distance // child buckets inside [Low, High], visited later
+	Pruned    []Distance // child buckets outside [Low, High], skipped
+}
+
+// Explain runs the same traversal as Search and returns one step per visited node,
+// in visiting order. Buckets are listed in ascending order. It is meant for
+// debugging (e.g. a metric violating the triangle inequality), use Search otherwise.
+func (tree *BKTree) Explain(val MetricTensor, radius Distance) []ExplainStep {
+	steps := make([]ExplainStep, 0, 10)
+	if tree.Root == nil || val == nil {
+		return steps
+	}
+	val = tree.normalize(val)
+	candidates := []*BkTreeNode{tree.Root}
+	for len(candidates) > 0 {
+		cand := candidates[0]
+		candidates = candidates[1:]
+		if cand.MetricTensor == nil {
+			// a nil value gives no bound, like Search every bucket is explored
+			step := ExplainStep{Low: 0, High: maxDistance, Explored: cand.sortedBuckets()}
+			for _, d := range step.Explored {
+				candidates = append(candidates, cand.Children[d])
+			}
+			steps = append(steps, step)
+			continue
+		}
+		dist := cand.DistanceFrom(val)
+		step := ExplainStep{
+			Value:    cand.MetricTensor,
+			Distance: dist,
+			Match:    dist <= radius,
+		}
+		step.Low, step.High = searchWindow(dist, radius)
+		for _, d := range cand.sortedBuckets() {
+			if d >= step.Low && d <= step.High {
+				step.Explored = append(step.Explored, d)
+				candidates = append(candidates, cand.Children[d])
+			} else {
+				step.Pruned = append(step.Pruned, d)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
@@ -0,0 +1,25 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBKTree_Explain(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"a", "ab", "abc", "d"})
+	steps := tree.Explain(Word("ab"), 0)
+	_, count := tree.Search(Word("ab"), 0)
+	if len(steps) != count {
+		t.Fatalf("expected: %d, got: %d", count, len(steps))
+	}
+	root := steps[0]
+	if root.Value != Word("a") || root.Distance != 1 || root.Match {
+		t.Errorf("unexpected root step: %+v", root)
+	}
+	if !reflect.DeepEqual(root.Explored, []Distance{1}) || !reflect.DeepEqual(root.Pruned, []Distance{2}) {
+		t.Errorf("expected explored [1] and pruned [2], got: %v and %v", root.Explored, root.Pruned)
+	}
+	if !steps[1].Match {
+		t.Errorf("expected %v to match", steps[1].Value)
+	}
+}
@@ -0,0 +1,132 @@
+package go_bk_tree
+
+import "hash/fnv"
+
+// Forest shards values across several independent trees, a value always lands
+// in the shard picked by the hash of its ToString
+type Forest struct {
+	Shards []*BKTree
+}
+
+// NewForest creates a forest of n empty shards, at least one
+func NewForest(n int) *Forest {
+	forest := &Forest{Shards: make([]*BKTree, max(n, 1))}
+	for i := range forest.Shards {
+		forest.Shards[i] = new(BKTree)
+	}
+	return forest
+}
+
+func (forest *Forest) shardOf(val MetricTensor) *BKTree {
+	h := fnv.New32a()
+	h.Write([]byte(val.ToString()))
+	return forest.Shards[h.Sum32()%uint32(len(forest.Shards))]
+}
+
+// Add a value to its shard
+func (forest *Forest) Add(val MetricTensor) error {
+	if val == nil {
+		return ErrNilValue
+	}
+	return forest.shardOf(val).Add(val)
+}
+
+// Size returns the total size of the shards
+func (forest *Forest) Size() int {
+	size := 0
+	for _, shard := range forest.Shards {
+		size += shard.Size
+	}
+	return size
+}
+
+// Search runs Search on every shard and concatenates the results
+func (forest *Forest) Search(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	count := 0
+	results := make([]MetricTensor, 0, 5)
+	for _, shard := range forest.Shards {
+		if shard.Root == nil {
+			continue
+		}
+		shardResults, shardCount := shard.Search(val, radius)
+		results = append(results, shardResults...)
+		count += shardCount
+	}
+	return results, count
+}
+
+// SearchKNN returns the k values of the whole forest closest to val. The shards share
+// a single best-first frontier, so the search stops as soon as no unexplored subtree of
+// any shard can beat the global k-th match, instead of running a full KNN per shard.
+// Each shard sees val through its own Normalizer: the shards seeing the same query (all
+// of them when they come from Shard) share a frontier, and the results are merged.
+func (forest *Forest) SearchKNN(val MetricTensor, k int) ([]Match, int) {
+	if val == nil {
+		return make([]Match, 0), 0
+	}
+	type group struct {
+		query MetricTensor
+		roots []*BkTreeNode
+	}
+	var groups []*group
+	byQuery := make(map[string]*group)
+	for _, shard := range forest.Shards {
+		if shard.Root == nil {
+			continue
+		}
+		query := shard.normalize(val)
+		key := query.ToString()
+		if n, ok := query.(Normalized); ok {
+			// ToString is the one of the original value, the distances use the key
+			key = "\x00" + n.Key.ToString()
+		}
+		g := byQuery[key]
+		if g == nil {
+			g = &group{query: query}
+			byQuery[key] = g
+			groups = append(groups, g)
+		}
+		g.roots = append(g.roots, shard.Root)
+	}
+	if len(groups) <= 1 {
+		var roots []*BkTreeNode
+		if len(groups) == 1 {
+			roots, val = groups[0].roots, groups[0].query
+		}
+		return searchKNN(roots, val, k)
+	}
+	results := make([]Match, 0, k)
+	count := 0
+	for _, g := range groups {
+		matches, n := searchKNN(g.roots, g.query, k)
+		count += n
+		for _, m := range matches {
+			results = insertMatch(results, m, k)
+		}
+	}
+	return results, count
+}
+
+// Shard splits the values of the tree into n independent trees, each value (with its
+// metadata) going to the shard Forest.Add would pick for it, so the shards hold about
+// Size/n values each and &Forest{Shards: tree.Shard(n)} keeps accepting values into
+// the right shard. The shards keep the Epsilon, ChildrenCap and Normalizer of the tree,
+// their sizes add up to Size and the tree itself is left untouched. Building them
+// costs a descent per value, like building a tree of Size/n values n times.
+func (tree *BKTree) Shard(n int) []*BKTree {
+	forest := &Forest{Shards: make([]*BKTree, max(n, 1))}
+	for i := range forest.Shards {
+		forest.Shards[i] = &BKTree{Epsilon: tree.Epsilon, ChildrenCap: tree.ChildrenCap, Normalizer: tree.Normalizer}
+	}
+	if tree.Root == nil {
+		return forest.Shards
+	}
+	for _, node := range collectNodes(tree.Root, nil) {
+		if node.MetricTensor == nil {
+			continue
+		}
+		copied := *node
+		forest.shardOf(node.MetricTensor).reinsert([]*BkTreeNode{&copied})
+	}
+	return forest.Shards
+}
@@ -0,0 +1,148 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func makeRandomHammingForest(size, shards int, seed int64) ([]Hamming64, *Forest) {
+	hashes, _ := makeRandomHammingTree(size, seed)
+	forest := NewForest(shards)
+	for _, h := range hashes {
+		forest.Add(h)
+	}
+	return hashes, forest
+}
+
+// naiveForestKNN runs a full KNN on every shard and merges the results
+func naiveForestKNN(forest *Forest, val MetricTensor, k int) ([]Match, int) {
+	count := 0
+	var all []Match
+	for _, shard := range forest.Shards {
+		results, shardCount := shard.SearchKNN(val, k)
+		all = append(all, results...)
+		count += shardCount
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Distance < all[j].Distance })
+	if len(all) > k {
+		all = all[:k]
+	}
+	return all, count
+}
+
+func TestForest_SearchKNN(t *testing.T) {
+	hashes, forest := makeRandomHammingForest(3000, 4, 7)
+	if forest.Size() != len(hashes) {
+		t.Errorf("expected: %d, got: %d", len(hashes), forest.Size())
+	}
+	query := Hamming64(987654321)
+	results, count := forest.SearchKNN(query, 10)
+	naive, naiveCount := naiveForestKNN(forest, query, 10)
+	if expected, got := matchDistances(naive), matchDistances(results); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+	if count > naiveCount {
+		t.Errorf("expected at most %d distance computations, got: %d", naiveCount, count)
+	}
+	matches, _ := forest.Search(hashes[0], 0)
+	if len(matches) != 1 {
+		t.Errorf("expected: %d, got: %d", 1, len(matches))
+	}
+}
+
+func BenchmarkForest_SearchKNN(b *testing.B) {
+	hashes, forest := makeRandomHammingForest(100000, 8, 1)
+	b.ResetTimer()
+
+	count := 0
+	for i := 0; i < b.N; i++ {
+		_, n := forest.SearchKNN(hashes[i%len(hashes)]^0xff, 10)
+		count += n
+	}
+	b.ReportMetric(float64(count)/float64(b.N), "distances/op")
+}
+
+func BenchmarkForest_SearchKNN_Naive(b *testing.B) {
+	hashes, forest := makeRandomHammingForest(100000, 8, 1)
+	b.ResetTimer()
+
+	count := 0
+	for i := 0; i < b.N; i++ {
+		_, n := naiveForestKNN(forest, hashes[i%len(hashes)]^0xff, 10)
+		count += n
+	}
+	b.ReportMetric(float64(count)/float64(b.N), "distances/op")
+}
+
+func TestBKTree_Shard(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(4000, 21)
+	tree.Add(hashes[0])
+	shards := tree.Shard(4)
+	if len(shards) != 4 {
+		t.Fatalf("expected 4 shards, got %d", len(shards))
+	}
+	total := 0
+	for _, shard := range shards {
+		if shard.Size < tree.Size/8 {
+			t.Errorf("expected balanced shards, got one of size %d out of %d", shard.Size, tree.Size)
+		}
+		total += shard.Size
+	}
+	if total != tree.Size {
+		t.Errorf("expected the shards to hold %d values, got %d", tree.Size, total)
+	}
+	forest := &Forest{Shards: shards}
+	for _, q := range hashes[:20] {
+		expected, _ := tree.Search(q, 14)
+		got, _ := forest.Search(q, 14)
+		if !reflect.DeepEqual(sortedStrings(got), sortedStrings(expected)) {
+			t.Errorf("%v: expected: %v, got: %v", q, len(expected), len(got))
+		}
+	}
+	if forest.shardOf(hashes[0]).Count(hashes[0]) != 2 || tree.Count(hashes[0]) != 2 {
+		t.Errorf("expected counts to be kept in the shard holding the value")
+	}
+}
+
+func TestForest_SearchKNN_Normalizer(t *testing.T) {
+	tree := &BKTree{Normalizer: lowerWord}
+	for _, w := range []string{"abc", "Hello", "world", "abd", "some", "soft", "Apple"} {
+		tree.Add(Word(w))
+	}
+	forest := &Forest{Shards: tree.Shard(3)}
+	if results, _ := forest.Search(Word("ABC"), 0); len(results) != 1 {
+		t.Errorf("expected 1 result, got: %v", results)
+	}
+	matches, _ := forest.SearchKNN(Word("ABC"), 1)
+	if len(matches) != 1 || matches[0].Distance != 0 || matches[0].Value.ToString() != "abc" {
+		t.Errorf("expected abc at 0, got: %v", matches)
+	}
+
+	// shards with different Normalizers each see their own query
+	plain := &BKTree{}
+	plain.Add(Word("ABD"))
+	lower := &BKTree{Normalizer: lowerWord}
+	lower.Add(Word("abc"))
+	mixed := &Forest{Shards: []*BKTree{plain, lower}}
+	matches, _ = mixed.SearchKNN(Word("ABC"), 2)
+	if got := matchDistances(matches); !reflect.DeepEqual(got, []Distance{0, 2}) {
+		t.Errorf("expected: %v, got: %v", []Distance{0, 2}, got)
+	}
+	if matches, _ := mixed.SearchKNN(nil, 2); len(matches) != 0 {
+		t.Errorf("expected no match for a nil query, got: %v", matches)
+	}
+}
+
+func TestNewForest_NoShards(t *testing.T) {
+	for _, n := range []int{0, -3} {
+		forest := NewForest(n)
+		if len(forest.Shards) != 1 {
+			t.Fatalf("%d: expected 1 shard, got %d", n, len(forest.Shards))
+		}
+		forest.Add(Hamming64(7))
+		if results, _ := forest.Search(Hamming64(7), 0); len(results) != 1 {
+			t.Errorf("%d: expected 1 result, got: %v", n, results)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package go_bk_tree
+
+// FreezeSubtree flattens the subtree rooted at the node holding val (found as Find
+// does) into the compact layout of FrozenTree, e.g. to freeze the stable core of a
+// tree whose tail keeps changing. The regular nodes are kept next to the copy, so a
+// frozen subtree takes its memory twice. Search (in any Traversal, Frontier or linear
+// scan mode) walks the copy instead of the nodes below, every other method (the other
+// searches, Find, the iterators, the serializers, the removals, ...) keeps using the
+// regular nodes and sees the same values.
+//
+// The copy is only valid while the subtree does not change: a value linked below the
+// node, or a node removed or relinked below it (RemoveNode, RemoveBulk, EvictOlderThan,
+// RebuildSubtree, Repair, ...), drops it and Search walks the regular nodes again until
+// FreezeSubtree is called once more. Adding a duplicate or changing the rest of the
+// tree keeps it. Children changed by hand are not noticed, call ThawSubtree first.
+//
+// It returns false if val is not in the tree. Frozen subtrees below the node are
+// replaced by the new copy, and nothing is done when the node already lies in a
+// frozen subtree.
+func (tree *BKTree) FreezeSubtree(val MetricTensor) bool {
+	node, covering := tree.findFrozen(val)
+	if node == nil {
+		return false
+	}
+	if covering == nil {
+		dropFrozen(node)
+		node.frozen = (&BKTree{Root: node}).Freeze()
+	}
+	return true
+}
+
+// ThawSubtree drops the copy made by FreezeSubtree of the subtree rooted at the node
+// holding val. It returns false if val is not in the tree or that node holds no copy.
+func (tree *BKTree) ThawSubtree(val MetricTensor) bool {
+	node, _ := tree.findFrozen(val)
+	if node == nil || node.frozen == nil {
+		return false
+	}
+	node.frozen = nil
+	return true
+}
+
+// findFrozen works like Find and also returns the node of the path to the one found
+// holding a frozen copy, that node included (nil if none). FreezeSubtree keeping a
+// single copy per path, later changes below the node found only invalidate that one.
+func (tree *BKTree) findFrozen(val MetricTensor) (*BkTreeNode, *BkTreeNode) {
+	if val == nil {
+		return nil, nil
+	}
+	val = tree.normalize(val)
+	var covering *BkTreeNode
+	curNode := tree.Root
+	for curNode != nil && curNode.MetricTensor != nil {
+		if covering == nil && curNode.frozen != nil {
+			covering = curNode
+		}
+		dist := curNode.DistanceFrom(val)
+		if dist <= tree.Epsilon {
+			return curNode, covering
+		}
+		curNode = curNode.Children[dist]
+	}
+	return nil, nil
+}
+
+// dropFrozen drops every frozen copy of the subtree rooted at node
+func dropFrozen(node *BkTreeNode) {
+	node.frozen = nil
+	for _, child := range node.Children {
+		dropFrozen(child)
+	}
+}
+
+// searchBelow appends the values of the frozen tree below its root within radius of
+// val to results, exploring it as Search does, dist being the distance between the
+// root and val, until they hold limit values (if limit is positive). It returns them
+// with the number of distance computations.
+func (ft *FrozenTree) searchBelow(val MetricTensor, radius, dist Distance, results []MetricTensor, limit int) ([]MetricTensor, int) {
+	count := 0
+	low, high := searchWindow(dist, radius)
+	first, last := ft.window(ft.nodes[0], low, high)
+	candidates := make([]int32, 0, 10)
+	for child := first; child < last; child++ {
+		candidates = append(candidates, child)
+	}
+	for len(candidates) > 0 {
+		cand := candidates[0]
+		candidates = candidates[1:]
+		if ft.values[cand] == nil {
+			for child := ft.nodes[cand].first; child < ft.nodes[cand].last; child++ {
+				candidates = append(candidates, child)
+			}
+			continue
+		}
+		dist := ft.values[cand].DistanceFrom(val)
+		count += 1
+		if dist <= radius {
+			results = append(results, ft.values[cand])
+			if limit > 0 && len(results) >= limit {
+				break
+			}
+		}
+		low, high := searchWindow(dist, radius)
+		first, last := ft.window(ft.nodes[cand], low, high)
+		for child := first; child < last; child++ {
+			candidates = append(candidates, child)
+		}
+	}
+	return results, count
+}
@@ -0,0 +1,249 @@
+package go_bk_tree
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// largestChild returns the child of the root with the largest subtree and its bucket
+func largestChild(tree *BKTree) (*BkTreeNode, Distance) {
+	var child *BkTreeNode
+	var bucket Distance
+	for d, c := range tree.Root.Children {
+		if child == nil || c.getSize() > child.getSize() {
+			child, bucket = c, d
+		}
+	}
+	return child, bucket
+}
+
+func hammingFactory(s string) MetricTensor {
+	h, _ := strconv.ParseUint(s, 16, 64)
+	return Hamming64(h)
+}
+
+func TestBKTree_FreezeSubtree(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 71)
+	_, reference := makeRandomHammingTree(3000, 71)
+	child, _ := largestChild(tree)
+	if !tree.FreezeSubtree(child.MetricTensor) || child.frozen == nil || len(child.Children) == 0 {
+		t.Fatal("expected the subtree to be frozen next to its nodes")
+	}
+	if child.frozen.Size != child.getSize() {
+		t.Errorf("expected a copy of %d values, got: %d", child.getSize(), child.frozen.Size)
+	}
+	if tree.FreezeSubtree(Hamming64(1)) {
+		t.Error("expected 1 not to be found")
+	}
+	for _, mode := range []struct {
+		traversal TraversalOrder
+		frontier  func() FrontierStrategy
+		scan      int
+	}{{BreadthFirst, nil, 0}, {DepthFirst, nil, 0}, {BreadthFirst, BestFirstFrontier, 0}, {BreadthFirst, nil, 5000}} {
+		tree.Traversal, tree.Frontier, tree.LinearScanBelow = mode.traversal, mode.frontier, mode.scan
+		for _, q := range hashes[:30] {
+			expected, _ := reference.Search(q, 14)
+			got, _ := tree.Search(q, 14)
+			if !reflect.DeepEqual(sortedStrings(got), sortedStrings(expected)) {
+				t.Errorf("%v: expected %d results, got %d", q, len(expected), len(got))
+			}
+		}
+	}
+	tree.Traversal, tree.Frontier, tree.LinearScanBelow = BreadthFirst, nil, 0
+	children := child.Children
+	child.Children = nil
+	expected, _ := reference.Search(hashes[1], 20)
+	if got, _ := tree.Search(hashes[1], 20); !reflect.DeepEqual(sortedStrings(got), sortedStrings(expected)) {
+		t.Errorf("expected Search to walk the copy, got %d results out of %d", len(got), len(expected))
+	}
+	child.Children = children
+
+	// the other methods keep using the regular nodes
+	if tree.Len() != reference.Size || tree.CountDistinct() != reference.Size {
+		t.Errorf("expected: %d, got: %d (%d counted)", reference.Size, tree.Len(), tree.CountDistinct())
+	}
+	if got, expected := sortedStrings(slices.Collect(tree.All())), sortedStrings(hashesOf(hashes)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected every value from All, got %d", len(got))
+	}
+	for _, q := range hashes[:10] {
+		expected, _ := reference.SearchKNN(q^0xff, 5)
+		if got, _ := tree.SearchKNN(q^0xff, 5); !reflect.DeepEqual(matchDistances(got), matchDistances(expected)) {
+			t.Errorf("%v: expected: %v, got: %v", q, matchDistances(expected), matchDistances(got))
+		}
+	}
+	_, small := makeRandomHammingTree(300, 71)
+	expectedPairs := len(small.AllPairsWithin(20))
+	smallChild, _ := largestChild(small)
+	small.FreezeSubtree(smallChild.MetricTensor)
+	if got := len(small.AllPairsWithin(20)); got != expectedPairs || expectedPairs == 0 {
+		t.Errorf("expected %d pairs, got: %d", expectedPairs, got)
+	}
+	var snapshot bytes.Buffer
+	if err := tree.WriteSnapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadWithChanges(&snapshot, nil, hammingFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sortedStrings(slices.Collect(loaded.All())); !reflect.DeepEqual(got, sortedStrings(hashesOf(hashes))) {
+		t.Errorf("expected every value in the snapshot, got %d", len(got))
+	}
+	for _, h := range hashes {
+		if tree.Find(h) == nil {
+			t.Fatalf("expected %v to be found", h)
+		}
+	}
+	if tree.Add(hashes[5]); tree.Size != reference.Size || tree.Count(hashes[5]) != 2 || child.frozen == nil {
+		t.Errorf("expected a duplicate to be counted and keep the copy, got size %d and count %d", tree.Size, tree.Count(hashes[5]))
+	}
+
+	if !tree.ThawSubtree(child.MetricTensor) || tree.ThawSubtree(child.MetricTensor) {
+		t.Error("expected the subtree to be thawed once")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func hashesOf(hashes []Hamming64) []MetricTensor {
+	vals := make([]MetricTensor, len(hashes))
+	for i, h := range hashes {
+		vals[i] = h
+	}
+	return vals
+}
+
+func TestBKTree_FreezeSubtree_Changes(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 72)
+	child, bucket := largestChild(tree)
+	check := func(name string, frozen bool) {
+		t.Helper()
+		if (child.frozen != nil) != frozen {
+			t.Errorf("%s: expected frozen: %v, got: %v", name, frozen, child.frozen != nil)
+		}
+		for _, q := range hashes[:20] {
+			expected, _ := tree.SearchExhaustive(q, 14)
+			if got, _ := tree.Search(q, 14); !reflect.DeepEqual(sortedStrings(got), sortedStrings(expected)) {
+				t.Errorf("%s, %v: expected %d results, got %d", name, q, len(expected), len(got))
+			}
+		}
+	}
+	r := rand.New(rand.NewSource(72))
+	added := func(below bool) Hamming64 {
+		for {
+			h := Hamming64(r.Uint64())
+			if (tree.Root.DistanceFrom(h) == bucket) == below && tree.Find(h) == nil {
+				tree.Add(h)
+				return h
+			}
+		}
+	}
+
+	tree.FreezeSubtree(child.MetricTensor)
+	added(false)
+	check("Add elsewhere", true)
+	h := added(true)
+	check("Add below", false)
+	if got, _ := tree.Search(h, 0); len(got) != 1 {
+		t.Errorf("expected the new value to be found, got: %v", got)
+	}
+
+	deepest := func() *BkTreeNode {
+		node := child
+		for len(node.Children) > 0 {
+			for _, c := range node.Children {
+				node = c
+				break
+			}
+		}
+		return node
+	}
+	tree.FreezeSubtree(child.MetricTensor)
+	if !tree.RemoveNode(deepest()) {
+		t.Fatal("expected the node to be removed")
+	}
+	check("RemoveNode", false)
+
+	tree.FreezeSubtree(child.MetricTensor)
+	leaf := deepest().MetricTensor
+	tree.RemoveBulk(func(val MetricTensor) bool { return val == leaf })
+	check("RemoveBulk", false)
+
+	tree.FreezeSubtree(child.MetricTensor)
+	tree.RebuildSubtree(tree.Root.MetricTensor, rand.New(rand.NewSource(1)))
+	if child.frozen != nil {
+		t.Error("RebuildSubtree: expected the copy to be dropped")
+	}
+	// the rebuild may have moved child, freeze the new largest subtree
+	child, bucket = largestChild(tree)
+	tree.FreezeSubtree(child.MetricTensor)
+	if n := tree.Repair(); n != 0 {
+		t.Errorf("expected no correction, got: %d", n)
+	}
+	check("Repair", true)
+
+	tree.Timestamps = true
+	h = added(true)
+	tree.FreezeSubtree(child.MetricTensor)
+	tree.Find(h).AddedAt = tree.Find(h).AddedAt.AddDate(-1, 0, 0)
+	if n := tree.EvictOlderThan(24 * time.Hour); n != 1 {
+		t.Errorf("expected: %d, got: %d", 1, n)
+	}
+	check("EvictOlderThan", false)
+	if tree.Size != tree.CountDistinct() {
+		t.Errorf("expected: %d, got: %d", tree.CountDistinct(), tree.Size)
+	}
+}
+
+func TestBKTree_FreezeSubtree_EvictOldest(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(500, 73)
+	tree.MaxSize, tree.Overflow, tree.Timestamps = tree.Size, EvictOldest, true
+	child, _ := largestChild(tree)
+	tree.FreezeSubtree(child.MetricTensor)
+	for _, h := range hashes[:50] {
+		if err := tree.Add(h ^ 0x10000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tree.Size != 500 || tree.CountDistinct() != 500 {
+		t.Errorf("expected: %d, got: %d (%d counted)", 500, tree.Size, tree.CountDistinct())
+	}
+	for _, h := range hashes[:50] {
+		if tree.Find(h^0x10000) == nil {
+			t.Errorf("expected %v to be added", h^0x10000)
+		}
+	}
+}
+
+func TestBKTree_FreezeSubtree_Nested(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(1000, 74)
+	child, _ := largestChild(tree)
+	var grandchild *BkTreeNode
+	for _, c := range child.Children {
+		grandchild = c
+		break
+	}
+	tree.FreezeSubtree(grandchild.MetricTensor)
+	tree.FreezeSubtree(child.MetricTensor)
+	if child.frozen == nil || grandchild.frozen != nil {
+		t.Error("expected the copy below to be replaced")
+	}
+	if !tree.FreezeSubtree(grandchild.MetricTensor) || grandchild.frozen != nil {
+		t.Error("expected nothing to be done inside a frozen subtree")
+	}
+	if tree.ThawSubtree(grandchild.MetricTensor) {
+		t.Error("expected no copy to drop below the frozen node")
+	}
+	for _, q := range hashes[:20] {
+		expected, _ := tree.SearchExhaustive(q, 14)
+		if got, _ := tree.Search(q, 14); !reflect.DeepEqual(sortedStrings(got), sortedStrings(expected)) {
+			t.Errorf("%v: expected %d results, got %d", q, len(expected), len(got))
+		}
+	}
+}
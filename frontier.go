@@ -0,0 +1,163 @@
+package go_bk_tree
+
+// FrontierStrategy holds the candidates of a Search waiting to be visited and picks
+// the next one, see BKTree.Frontier. Whatever the order, a search visits the same
+// nodes and returns the same values: the order only changes the memory taken by the
+// frontier and which matches come first, which matters when MaxResults stops the
+// search early. On 100000 random Hamming64 values with radius 20
+// (BenchmarkBKTree_Search_*Frontier) FIFOFrontier and LIFOFrontier are on par with
+// the BreadthFirst and DepthFirst traversals, and BestFirstFrontier is ~2x slower.
+type FrontierStrategy interface {
+	// Push adds a candidate, bound is a lower bound of the distance between the query
+	// and any value of its subtree
+	Push(node *BkTreeNode, bound Distance)
+	// Pop removes the next candidate to visit, ok is false when there is none left
+	Pop() (node *BkTreeNode, ok bool)
+}
+
+// FIFOFrontier visits the candidates in the order they were found, level by level,
+// like the default BreadthFirst traversal
+func FIFOFrontier() FrontierStrategy {
+	return &fifoFrontier{}
+}
+
+type fifoFrontier struct {
+	nodes []*BkTreeNode
+}
+
+func (f *fifoFrontier) Push(node *BkTreeNode, bound Distance) {
+	f.nodes = append(f.nodes, node)
+}
+
+func (f *fifoFrontier) Pop() (*BkTreeNode, bool) {
+	if len(f.nodes) == 0 {
+		return nil, false
+	}
+	node := f.nodes[0]
+	f.nodes = f.nodes[1:]
+	return node, true
+}
+
+// LIFOFrontier visits the last candidate found first, like the DepthFirst traversal
+func LIFOFrontier() FrontierStrategy {
+	return &lifoFrontier{}
+}
+
+type lifoFrontier struct {
+	nodes []*BkTreeNode
+}
+
+func (f *lifoFrontier) Push(node *BkTreeNode, bound Distance) {
+	f.nodes = append(f.nodes, node)
+}
+
+func (f *lifoFrontier) Pop() (*BkTreeNode, bool) {
+	if len(f.nodes) == 0 {
+		return nil, false
+	}
+	node := f.nodes[len(f.nodes)-1]
+	f.nodes = f.nodes[:len(f.nodes)-1]
+	return node, true
+}
+
+// BestFirstFrontier visits the candidate with the lowest bound first, so that the
+// closest matches tend to be found first, at the cost of a heap operation per node
+func BestFirstFrontier() FrontierStrategy {
+	return &bestFirstFrontier{}
+}
+
+// bestFirstFrontier is a binary min-heap of its own rather than a NodeHeap driven by
+// container/heap, which would allocate to box every pushed ScoredNode
+type bestFirstFrontier struct {
+	nodes []ScoredNode
+}
+
+func (f *bestFirstFrontier) Push(node *BkTreeNode, bound Distance) {
+	f.nodes = append(f.nodes, ScoredNode{node, bound})
+	i := len(f.nodes) - 1
+	for i > 0 {
+		parent := (i - 1) / 2
+		if f.nodes[parent].Bound <= f.nodes[i].Bound {
+			break
+		}
+		f.nodes[parent], f.nodes[i] = f.nodes[i], f.nodes[parent]
+		i = parent
+	}
+}
+
+func (f *bestFirstFrontier) Pop() (*BkTreeNode, bool) {
+	if len(f.nodes) == 0 {
+		return nil, false
+	}
+	top := f.nodes[0].Node
+	last := len(f.nodes) - 1
+	f.nodes[0] = f.nodes[last]
+	f.nodes = f.nodes[:last]
+	i := 0
+	for {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < last && f.nodes[left].Bound < f.nodes[smallest].Bound {
+			smallest = left
+		}
+		if right < last && f.nodes[right].Bound < f.nodes[smallest].Bound {
+			smallest = right
+		}
+		if smallest == i {
+			return top, true
+		}
+		f.nodes[i], f.nodes[smallest] = f.nodes[smallest], f.nodes[i]
+		i = smallest
+	}
+}
+
+// searchFrontier is searchNodes driven by a FrontierStrategy from tree.Frontier
+func (tree *BKTree) searchFrontier(val MetricTensor, radius Distance, resultCap int) ([]MetricTensor, int) {
+	count := 0
+	results := make([]MetricTensor, 0, resultCap)
+	var visited []Match
+	if tree.MetricCheck != nil {
+		defer func() { tree.MetricCheck.check(val, visited) }()
+	}
+	frontier := tree.Frontier()
+	frontier.Push(tree.Root, 0)
+	for {
+		cand, ok := frontier.Pop()
+		if !ok {
+			break
+		}
+		if cand.MetricTensor == nil {
+			// a nil value gives no bound, explore every child
+			for _, child := range cand.Children {
+				frontier.Push(child, 0)
+			}
+			continue
+		}
+		dist := cand.DistanceFrom(val)
+		count += 1
+		if tree.MetricCheck != nil {
+			visited = append(visited, Match{cand.MetricTensor, dist})
+		}
+		if dist <= radius {
+			results = append(results, cand.MetricTensor)
+			if tree.capped(len(results)) {
+				break
+			}
+		}
+		if cand.frozen != nil {
+			var frozenCount int
+			results, frozenCount = cand.frozen.searchBelow(val, radius, dist, results, tree.MaxResults)
+			count += frozenCount
+			if tree.capped(len(results)) {
+				break
+			}
+			continue
+		}
+		low, high := searchWindow(dist, radius)
+		for d, child := range cand.Children {
+			if d >= low && d <= high {
+				frontier.Push(child, diffDistance(dist, d))
+			}
+		}
+	}
+	return results, count
+}
@@ -0,0 +1,243 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+var ErrNotHamming = errors.New("go_bk_tree: frozen tree contains values other than Hamming64")
+
+type frozenNode struct {
+	dist        Distance // distance from parent
+	first, last int32    // children occupy nodes[first:last]
+}
+
+// FrozenTree is a read-only, array-backed copy of a BKTree. Nodes are laid out
+// in breadth-first order so that the children of a node are contiguous and
+// sorted by their distance from the parent: Search finds the children in its
+// window by binary search instead of scanning a map of every child bucket.
+type FrozenTree struct {
+	Size int
+	// MaxResults, when positive, caps the number of values returned by Search, which
+	// stops at the cap as BKTree.Search does. Freeze copies it from the tree.
+	MaxResults int
+	nodes      []frozenNode
+	values     []MetricTensor
+	// hashes mirrors values when every value is a Hamming64,
+	// so that batch searches never go through the interface
+	hashes []uint64
+}
+
+// Freeze flattens the tree into a FrozenTree. Later changes to the tree
+// are not reflected in the frozen copy.
+func (tree *BKTree) Freeze() *FrozenTree {
+	ft := &FrozenTree{MaxResults: tree.MaxResults}
+	if tree.Root == nil {
+		return ft
+	}
+	queue := []*BkTreeNode{tree.Root}
+	ft.nodes = append(ft.nodes, frozenNode{})
+	ft.values = append(ft.values, tree.Root.MetricTensor)
+	for i := 0; i < len(queue); i++ {
+		cur := queue[i]
+		ft.nodes[i].first = int32(len(ft.nodes))
+		for _, dist := range cur.sortedBuckets() {
+			child := cur.Children[dist]
+			queue = append(queue, child)
+			ft.nodes = append(ft.nodes, frozenNode{dist: dist})
+			ft.values = append(ft.values, child.MetricTensor)
+		}
+		ft.nodes[i].last = int32(len(ft.nodes))
+	}
+	ft.Size = len(ft.nodes)
+	ft.setHashes()
+	return ft
+}
+
+// freezeChunk is the minimum number of nodes of a level sorted by one goroutine
+const freezeChunk = 256
+
+// FreezeParallel produces the same FrozenTree as Freeze, sorting the children of
+// each level concurrently with at most NumCPU goroutines. The breadth-first layout
+// interleaves the subtrees of the root on every level, so it cannot be assembled
+// from independently flattened subtrees: levels are processed one after the other
+// and only the placement of the sorted children, which is cheap, is sequential.
+func (tree *BKTree) FreezeParallel() *FrozenTree {
+	ft := &FrozenTree{MaxResults: tree.MaxResults}
+	if tree.Root == nil {
+		return ft
+	}
+	ft.nodes = append(ft.nodes, frozenNode{})
+	ft.values = append(ft.values, tree.Root.MetricTensor)
+	level := []*BkTreeNode{tree.Root}
+	start := 0
+	for len(level) > 0 {
+		buckets := make([][]Distance, len(level))
+		sem := make(chan struct{}, numCPU)
+		var wg sync.WaitGroup
+		for lo := 0; lo < len(level); lo += freezeChunk {
+			hi := min(lo+freezeChunk, len(level))
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(lo, hi int) {
+				defer wg.Done()
+				for i := lo; i < hi; i++ {
+					buckets[i] = level[i].sortedBuckets()
+				}
+				<-sem
+			}(lo, hi)
+		}
+		wg.Wait()
+		next := make([]*BkTreeNode, 0, len(level))
+		for i, cur := range level {
+			ft.nodes[start+i].first = int32(len(ft.nodes))
+			for _, dist := range buckets[i] {
+				child := cur.Children[dist]
+				next = append(next, child)
+				ft.nodes = append(ft.nodes, frozenNode{dist: dist})
+				ft.values = append(ft.values, child.MetricTensor)
+			}
+			ft.nodes[start+i].last = int32(len(ft.nodes))
+		}
+		start += len(level)
+		level = next
+	}
+	ft.Size = len(ft.nodes)
+	ft.setHashes()
+	return ft
+}
+
+// setHashes fills hashes if every value is a Hamming64
+func (ft *FrozenTree) setHashes() {
+	ft.hashes = make([]uint64, len(ft.values))
+	for i, v := range ft.values {
+		h, ok := v.(Hamming64)
+		if !ok {
+			ft.hashes = nil
+			break
+		}
+		ft.hashes[i] = uint64(h)
+	}
+}
+
+// Search works like BKTree.Search but walks the flattened arrays
+func (ft *FrozenTree) Search(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	if len(ft.nodes) == 0 || val == nil {
+		return results, 0
+	}
+	count := 0
+	candidates := make([]int32, 0, 10)
+	candidates = append(candidates, 0)
+	for len(candidates) > 0 {
+		cand := candidates[0]
+		candidates = candidates[1:]
+		if ft.values[cand] == nil {
+			// a nil value gives no bound, explore every child
+			for child := ft.nodes[cand].first; child < ft.nodes[cand].last; child++ {
+				candidates = append(candidates, child)
+			}
+			continue
+		}
+		dist := ft.values[cand].DistanceFrom(val)
+		count += 1
+		if dist <= radius {
+			results = append(results, ft.values[cand])
+			if ft.MaxResults > 0 && len(results) >= ft.MaxResults {
+				break
+			}
+		}
+		low, high := searchWindow(dist, radius)
+		first, last := ft.window(ft.nodes[cand], low, high)
+		for child := first; child < last; child++ {
+			candidates = append(candidates, child)
+		}
+	}
+	return results, count
+}
+
+// SearchHammingBatch runs a radius search for every query and returns the matches
+// index-aligned with queries. Distances are computed with popcount directly on the
+// contiguous hash storage, so it is only available when the frozen tree contains
+// nothing but Hamming64 values. It fails with ErrEmptyTree on an empty tree.
+func (ft *FrozenTree) SearchHammingBatch(queries []Hamming64, radius Distance) ([][]Hamming64, error) {
+	if len(ft.nodes) == 0 {
+		return nil, ErrEmptyTree
+	}
+	if ft.hashes == nil {
+		return nil, ErrNotHamming
+	}
+	results := make([][]Hamming64, len(queries))
+	hashes, nodes := ft.hashes, ft.nodes
+	stack := make([]int32, 0, 64)
+	for qi, query := range queries {
+		q := uint64(query)
+		stack = append(stack[:0], 0)
+		for len(stack) > 0 {
+			cand := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			dist := Distance(bits.OnesCount64(hashes[cand] ^ q))
+			if dist <= radius {
+				results[qi] = append(results[qi], Hamming64(hashes[cand]))
+			}
+			low, high := searchWindow(dist, radius)
+			node := nodes[cand]
+			for child := node.first; child < node.last; child++ {
+				d := nodes[child].dist
+				if d > high {
+					// children are sorted by distance
+					break
+				}
+				if d >= low {
+					stack = append(stack, child)
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// window returns the range of the children of node whose distance is in [low, high].
+// Children are sorted by distance, so the first one is found by binary search and the
+// range is then scanned up to high, which is cheaper than a second binary search for
+// the narrow windows of small radii.
+func (ft *FrozenTree) window(node frozenNode, low, high Distance) (int32, int32) {
+	first, last := node.first, node.last
+	for first < last {
+		mid := int32(uint32(first+last) >> 1)
+		if ft.nodes[mid].dist < low {
+			first = mid + 1
+		} else {
+			last = mid
+		}
+	}
+	last = first
+	for last < node.last && ft.nodes[last].dist <= high {
+		last++
+	}
+	return first, last
+}
+
+// Warmup reads all the node storage of the frozen tree sequentially so that it is
+// faulted in and cached before the first queries, which smooths their latency after
+// loading a large index. It returns a cheap checksum of the tree layout (and hashes),
+// the values behind the MetricTensor interfaces are not touched.
+func (ft *FrozenTree) Warmup() uint64 {
+	const prime = 1099511628211
+	sum := uint64(14695981039346656037)
+	for _, node := range ft.nodes {
+		sum = (sum ^ uint64(node.dist)) * prime
+		sum = (sum ^ uint64(node.first)<<32 ^ uint64(node.last)) * prime
+	}
+	for _, h := range ft.hashes {
+		sum = (sum ^ h) * prime
+	}
+	nonNil := 0
+	for _, v := range ft.values {
+		if v != nil {
+			nonNil++
+		}
+	}
+	return (sum ^ uint64(nonNil)) * prime
+}
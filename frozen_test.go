@@ -0,0 +1,152 @@
+package go_bk_tree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func makeRandomHammingTree(size int, seed int64) ([]Hamming64, *BKTree) {
+	r := rand.New(rand.NewSource(seed))
+	hashes := make([]Hamming64, size)
+	tree := new(BKTree)
+	for i := range hashes {
+		hashes[i] = Hamming64(r.Uint64())
+		tree.Add(hashes[i])
+	}
+	return hashes, tree
+}
+
+func sortedStrings(vals []MetricTensor) []string {
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = v.ToString()
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+func TestFrozenTree_SearchHammingBatch(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 1)
+	frozen := tree.Freeze()
+	if frozen.Size != tree.Size {
+		t.Fatalf("expected: %d, got: %d", tree.Size, frozen.Size)
+	}
+	queries := hashes[:50]
+	batch, err := frozen.SearchHammingBatch(queries, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, q := range queries {
+		expected, _ := tree.Search(q, 20)
+		got := make([]MetricTensor, len(batch[i]))
+		for j, h := range batch[i] {
+			got[j] = h
+		}
+		if e, g := sortedStrings(expected), sortedStrings(got); !reflect.DeepEqual(e, g) {
+			t.Errorf("query %d: expected: %v, got: %v", i, e, g)
+		}
+	}
+	if _, err := createNewTreeFromWords([]string{"a"}).Freeze().SearchHammingBatch(queries, 1); err != ErrNotHamming {
+		t.Errorf("expected: %v, got: %v", ErrNotHamming, err)
+	}
+}
+
+func TestBKTree_FreezeParallel(t *testing.T) {
+	_, tree := makeRandomHammingTree(20000, 1)
+	for _, tr := range []*BKTree{tree, createNewTreeFromWords([]string{"some", "soft", "same", "mole", "soda"}), new(BKTree)} {
+		if expected, got := tr.Freeze(), tr.FreezeParallel(); !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected FreezeParallel to match Freeze for a tree of size %d", tr.Size)
+		}
+	}
+}
+
+func BenchmarkBKTree_Freeze(b *testing.B) {
+	_, tree := makeRandomHammingTree(1000000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Freeze()
+	}
+}
+
+func BenchmarkBKTree_FreezeParallel(b *testing.B) {
+	_, tree := makeRandomHammingTree(1000000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.FreezeParallel()
+	}
+}
+
+func BenchmarkBKTree_Search_Hamming(b *testing.B) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(hashes[i%len(hashes)], 8)
+	}
+}
+
+func BenchmarkFrozenTree_Search_Hamming(b *testing.B) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	frozen := tree.Freeze()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		frozen.Search(hashes[i%len(hashes)], 8)
+	}
+}
+
+func BenchmarkFrozenTree_SearchHammingBatch(b *testing.B) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	frozen := tree.Freeze()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		frozen.SearchHammingBatch(hashes[i%len(hashes):i%len(hashes)+1], 8)
+	}
+}
+
+func TestFrozenTree_Warmup(t *testing.T) {
+	_, tree := makeRandomHammingTree(500, 10)
+	a, b := tree.Freeze().Warmup(), tree.Freeze().Warmup()
+	if a != b {
+		t.Errorf("expected the same checksum, got: %x and %x", a, b)
+	}
+	tree.Add(Hamming64(42))
+	if c := tree.Freeze().Warmup(); c == a {
+		t.Error("expected the checksum to change with the tree")
+	}
+}
+
+func makeWideTree(size int, seed int64) ([]bigNumber, *BKTree) {
+	r := rand.New(rand.NewSource(seed))
+	vals := make([]bigNumber, size)
+	tree := new(BKTree)
+	for i := range vals {
+		vals[i] = bigNumber(r.Intn(1 << 30))
+		tree.Add(vals[i])
+	}
+	return vals, tree
+}
+
+func BenchmarkBKTree_Search_WideFanout(b *testing.B) {
+	vals, tree := makeWideTree(100000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(vals[i%len(vals)], 1000)
+	}
+}
+
+func BenchmarkFrozenTree_Search_WideFanout(b *testing.B) {
+	vals, tree := makeWideTree(100000, 1)
+	frozen := tree.Freeze()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		frozen.Search(vals[i%len(vals)], 1000)
+	}
+}
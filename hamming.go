@@ -0,0 +1,18 @@
+package go_bk_tree
+
+import (
+	"math/bits"
+	"strconv"
+)
+
+// Hamming64 is a built-in MetricTensor for 64-bit hashes (e.g. perceptual image hashes),
+// the distance between two values is the number of differing bits
+type Hamming64 uint64
+
+func (h Hamming64) DistanceFrom(other MetricTensor) Distance {
+	return Distance(bits.OnesCount64(uint64(h) ^ uint64(other.(Hamming64))))
+}
+
+func (h Hamming64) ToString() string {
+	return strconv.FormatUint(uint64(h), 16)
+}
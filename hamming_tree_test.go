@@ -0,0 +1,93 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func makeHammingTree(hashes []Hamming64) *HammingTree {
+	tree := new(HammingTree)
+	for _, h := range hashes {
+		tree.Add(uint64(h))
+	}
+	return tree
+}
+
+func sortedHashes(hashes []uint64) []uint64 {
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
+func TestHammingTree(t *testing.T) {
+	hashes, generic := makeRandomHammingTree(3000, 14)
+	tree := makeHammingTree(hashes)
+	if tree.Add(uint64(hashes[0])) || tree.Size != generic.Size {
+		t.Errorf("expected a duplicate to be dropped, size: %d", tree.Size)
+	}
+	frozen := tree.Freeze()
+	for _, q := range hashes[:30] {
+		q ^= 0xf0f
+		expected, _ := generic.Search(q, 14)
+		want := make([]uint64, len(expected))
+		for i, v := range expected {
+			want[i] = uint64(v.(Hamming64))
+		}
+		want = sortedHashes(want)
+		if got := sortedHashes(tree.Search(uint64(q), 14)); !reflect.DeepEqual(want, got) {
+			t.Errorf("expected: %v, got: %v", want, got)
+		}
+		if got := sortedHashes(frozen.Search(uint64(q), 14)); !reflect.DeepEqual(want, got) {
+			t.Errorf("frozen: expected: %v, got: %v", want, got)
+		}
+		knn, _ := generic.SearchKNN(q, 7)
+		for _, got := range [][]HammingMatch{tree.SearchKNN(uint64(q), 7), frozen.SearchKNN(uint64(q), 7)} {
+			dists := make([]Distance, len(got))
+			for i, m := range got {
+				dists[i] = m.Distance
+			}
+			if expected := matchDistances(knn); !reflect.DeepEqual(expected, dists) {
+				t.Errorf("expected: %v, got: %v", expected, dists)
+			}
+		}
+	}
+}
+
+func BenchmarkHammingTree_Search(b *testing.B) {
+	hashes, _ := makeRandomHammingTree(100000, 1)
+	tree := makeHammingTree(hashes)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Search(uint64(hashes[i%len(hashes)]), 8)
+	}
+}
+
+func BenchmarkFrozenHammingTree_Search(b *testing.B) {
+	hashes, _ := makeRandomHammingTree(100000, 1)
+	frozen := makeHammingTree(hashes).Freeze()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		frozen.Search(uint64(hashes[i%len(hashes)]), 8)
+	}
+}
+
+func BenchmarkHammingTree_SearchKNN(b *testing.B) {
+	hashes, _ := makeRandomHammingTree(100000, 1)
+	tree := makeHammingTree(hashes)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.SearchKNN(uint64(hashes[i%len(hashes)]), 10)
+	}
+}
+
+func BenchmarkBKTree_SearchKNN_Hamming(b *testing.B) {
+	hashes, tree := makeRandomHammingTree(100000, 1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.SearchKNN(hashes[i%len(hashes)], 10)
+	}
+}
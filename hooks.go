@@ -0,0 +1,33 @@
+package go_bk_tree
+
+import "time"
+
+// Hooks are callbacks called synchronously after the operations on a tree, e.g. to
+// emit metrics or trace spans. Any of them can be nil, and a tree without Hooks does
+// not even read the clock. They must not modify the tree.
+type Hooks struct {
+	// OnAdd is called by Add and AddWithRootDistance with the value and the error
+	// returned, which is nil for a duplicate as well as for an added value (nil
+	// values are rejected before reaching the hook)
+	OnAdd func(val MetricTensor, err error)
+	// OnSearch is called by Search and SearchWithHint
+	OnSearch func(val MetricTensor, radius Distance, stats SearchStats)
+	// OnRemove is called by RemoveNode (including the evictions of MaxSize) with the
+	// removed value and the number of values of its subtree that were reinserted
+	OnRemove func(val MetricTensor, reinserted int)
+}
+
+// SearchStats describes a finished search for Hooks.OnSearch
+type SearchStats struct {
+	Results  int
+	Visited  int // number of distance computations
+	Duration time.Duration
+}
+
+// observeAdd reports the outcome of an Add to Hooks.OnAdd and returns err
+func (tree *BKTree) observeAdd(val MetricTensor, err error) error {
+	if tree.Hooks != nil && tree.Hooks.OnAdd != nil {
+		tree.Hooks.OnAdd(val, err)
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBKTree_Hooks(t *testing.T) {
+	var added, failed, searches, removed int
+	var stats SearchStats
+	tree := &BKTree{Hooks: &Hooks{
+		OnAdd: func(val MetricTensor, err error) {
+			added++
+			if err != nil {
+				failed++
+			}
+		},
+		OnSearch: func(val MetricTensor, radius Distance, s SearchStats) {
+			searches++
+			stats = s
+		},
+		OnRemove: func(val MetricTensor, reinserted int) {
+			if val.ToString() != "some" || reinserted != 3 {
+				t.Errorf("unexpected removal of %v with %d reinserted", val, reinserted)
+			}
+			removed++
+		},
+	}}
+	for _, w := range []string{"some", "soft", "same", "some"} {
+		tree.Add(Word(w))
+	}
+	tree.Add(nil)
+	tree.AddWithRootDistance(Word("mole"), Word("some").DistanceFrom(Word("mole")))
+	if added != 5 || failed != 0 {
+		t.Errorf("expected 5 reported adds without errors, got: %d, %d", added, failed)
+	}
+	results, count := tree.Search(Word("sole"), 2)
+	if searches != 1 || stats.Results != len(results) || stats.Visited != count {
+		t.Errorf("unexpected search stats: %+v after %d searches", stats, searches)
+	}
+	tree.RemoveNode(tree.Find(Word("some")))
+	if removed != 1 {
+		t.Errorf("expected: %d, got: %d", 1, removed)
+	}
+
+	full := &BKTree{MaxSize: 1, Hooks: &Hooks{OnAdd: func(val MetricTensor, err error) {
+		if !errors.Is(err, ErrTreeFull) && val.ToString() == "soft" {
+			t.Errorf("expected: %v, got: %v", ErrTreeFull, err)
+		}
+	}}}
+	full.Add(Word("some"))
+	full.Add(Word("soft"))
+}
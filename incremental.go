@@ -0,0 +1,163 @@
+package go_bk_tree
+
+import "slices"
+
+// IncrementalQuery is implemented by query values whose distance to a value can be
+// resumed as the query grows (e.g. text being typed) instead of being recomputed,
+// see StreamingSearch. ResumeDistance returns the distance between the query and
+// other, which must equal DistanceFrom, along with a state to pass back for other
+// with a later query. state is nil the first time, it is owned by the implementation
+// which must start over when it does not apply (e.g. the new query is not an
+// extension of the one the state was computed for). The metric must be symmetric.
+type IncrementalQuery interface {
+	MetricTensor
+	ResumeDistance(other MetricTensor, state any) (Distance, any)
+}
+
+// StreamingSearch runs successive searches for a query that grows between them. It
+// keeps the IncrementalQuery state of every node visited so far, so each search only
+// pays for the part of the query added since the previous one. Queries not
+// implementing IncrementalQuery, and queries on a tree with a Normalizer, are searched
+// as by Search. The tree must not change
+// while the StreamingSearch is in use, and a StreamingSearch is not safe for
+// concurrent use. The states take memory for every node visited, see Reset.
+type StreamingSearch struct {
+	tree   *BKTree
+	states map[*BkTreeNode]any
+}
+
+// NewStreamingSearch creates a StreamingSearch over the tree
+func (tree *BKTree) NewStreamingSearch() *StreamingSearch {
+	return &StreamingSearch{tree: tree, states: make(map[*BkTreeNode]any)}
+}
+
+// Reset drops the kept states, e.g. when the user starts typing another query
+func (s *StreamingSearch) Reset() {
+	clear(s.states)
+}
+
+// Search returns the values within radius of query and the number of visited nodes
+func (s *StreamingSearch) Search(query MetricTensor, radius Distance) ([]MetricTensor, int) {
+	incremental, ok := query.(IncrementalQuery)
+	if !ok || s.tree.Normalizer != nil {
+		return s.tree.Search(query, radius)
+	}
+	results := make([]MetricTensor, 0, 5)
+	count := s.tree.traverseNodes(func(node *BkTreeNode) Distance {
+		dist, state := incremental.ResumeDistance(node.MetricTensor, s.states[node])
+		s.states[node] = state
+		return dist
+	}, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			results = append(results, node.MetricTensor)
+		}
+		return !s.tree.capped(len(results))
+	})
+	return results, count
+}
+
+// editState is the IncrementalQuery state of EditString: the last row of the
+// distance matrix after the runes of query
+type editState struct {
+	query []rune
+	row   []int
+}
+
+// ResumeDistance implements IncrementalQuery, growing the query by n runes costs n
+// rows of the distance matrix
+func (s EditString) ResumeDistance(other MetricTensor, state any) (Distance, any) {
+	o, q := []rune(string(other.(EditString))), []rune(string(s))
+	st, _ := state.(*editState)
+	if st == nil || len(st.query) > len(q) || !slices.Equal(st.query, q[:len(st.query)]) {
+		st = &editState{row: make([]int, len(o)+1)}
+		for j := range st.row {
+			st.row[j] = j
+		}
+	}
+	for i := len(st.query); i < len(q); i++ {
+		diag := st.row[0]
+		st.row[0] = i + 1
+		for j := 1; j <= len(o); j++ {
+			sub := diag
+			if q[i] != o[j-1] {
+				sub++
+			}
+			diag = st.row[j]
+			st.row[j] = min(sub, st.row[j]+1, st.row[j-1]+1)
+		}
+	}
+	st.query = q
+	return Distance(st.row[len(o)]), st
+}
+
+// WarmSearch answers the searches of a query edited a little at a time (e.g. typeahead
+// with corrections, unlike StreamingSearch which needs a growing query) from the matches
+// of a previous, wider search. A search for radius r runs on the tree with radius
+// r+Slack and its matches are kept; a later query q within d of the kept query is then
+// answered from the kept matches when d+r is at most the kept radius, which is safe by
+// the triangle inequality: any value within r of q is within d+r of the kept query.
+// Otherwise it falls back to a new search on the tree. A larger Slack serves more edits
+// from the kept matches but makes each fallback search wider. The kept matches are
+// dropped when the Version of the tree changes. It is not safe for concurrent use.
+type WarmSearch struct {
+	Slack Distance
+
+	tree    *BKTree
+	query   MetricTensor
+	radius  Distance
+	version uint64
+	matches []Match // with their distance from query
+}
+
+// NewWarmSearch creates a WarmSearch over the tree
+func (tree *BKTree) NewWarmSearch(slack Distance) *WarmSearch {
+	return &WarmSearch{Slack: slack, tree: tree}
+}
+
+// Search returns the values within radius of val and the number of distance
+// computations, including the one between val and the kept query. The results are
+// those of Search, in another order.
+func (ws *WarmSearch) Search(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	if val == nil {
+		return results, 0
+	}
+	val = ws.tree.normalize(val)
+	if ws.query != nil && ws.version == ws.tree.Version() {
+		d := val.DistanceFrom(ws.query)
+		count := 1
+		if addDistance(d, radius) <= ws.radius {
+			for _, m := range ws.matches {
+				if ws.tree.capped(len(results)) {
+					break
+				}
+				// the triangle inequality rules m out without computing its distance
+				if diffDistance(m.Distance, d) > radius {
+					continue
+				}
+				count++
+				if m.Value.DistanceFrom(val) <= radius {
+					results = append(results, m.Value)
+				}
+			}
+			return ws.tree.collapse(val, results), count
+		}
+	}
+	ws.query, ws.radius, ws.version = val, addDistance(radius, ws.Slack), ws.tree.Version()
+	ws.matches = ws.matches[:0]
+	count := ws.tree.traverse(val, ws.radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= ws.radius {
+			ws.matches = append(ws.matches, Match{node.MetricTensor, dist})
+		}
+		return true
+	})
+	for _, m := range ws.matches {
+		if ws.tree.capped(len(results)) {
+			break
+		}
+		if m.Distance <= radius {
+			results = append(results, m.Value)
+		}
+	}
+	return ws.tree.collapse(val, results), count
+}
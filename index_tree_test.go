@@ -0,0 +1,73 @@
+package go_bk_tree
+
+import (
+	"math/bits"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+func hammingDistance(a, b uint64) Distance {
+	return Distance(bits.OnesCount64(a ^ b))
+}
+
+func TestIndexTree_Search(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 4)
+	values := make([]uint64, len(hashes)+1)
+	for i, h := range hashes {
+		values[i] = uint64(h)
+	}
+	values[len(hashes)] = values[0]
+	indexed := NewIndexTree(values, hammingDistance)
+	if err := indexed.AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if indexed.Size != tree.Size {
+		t.Errorf("expected: %d, got: %d", tree.Size, indexed.Size)
+	}
+	for _, q := range hashes[:50] {
+		expected, _ := tree.Search(q, 12)
+		got, _ := indexed.Search(uint64(q), 12)
+		gotStrings := make([]string, len(got))
+		for i, idx := range got {
+			gotStrings[i] = Hamming64(values[idx]).ToString()
+		}
+		sort.Strings(gotStrings)
+		if e := sortedStrings(expected); !reflect.DeepEqual(e, gotStrings) {
+			t.Errorf("expected: %v, got: %v", e, gotStrings)
+		}
+	}
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func BenchmarkIndexTree_Memory(b *testing.B) {
+	hashes, _ := makeRandomHammingTree(100000, 1)
+	values := make([]uint64, len(hashes))
+	for i, h := range hashes {
+		values[i] = uint64(h)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		before := heapInUse()
+		tree := new(BKTree)
+		for _, h := range hashes {
+			tree.Add(h)
+		}
+		b.ReportMetric(float64(heapInUse()-before)/float64(len(hashes)), "B/node(BKTree)")
+		runtime.KeepAlive(tree)
+
+		before = heapInUse()
+		indexed := NewIndexTree(values, hammingDistance)
+		indexed.AddAll()
+		b.ReportMetric(float64(heapInUse()-before)/float64(len(hashes)), "B/node(IndexTree)")
+		runtime.KeepAlive(indexed)
+	}
+}
@@ -0,0 +1,32 @@
+package go_bk_tree
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net/netip"
+)
+
+// IPAddr is a built-in MetricTensor for IP addresses, the distance between two
+// addresses is the number of differing bits of their 16-byte form (IPv4 addresses
+// taken as ::ffff:a.b.c.d), plus 1 when one is an IPv4 address and the other an IPv6
+// one, as if the address family were one more bit. So 1.2.3.4 and ::ffff:1.2.3.4 are
+// 1 apart and not duplicates, and an IPv4 address is closest to the IPv6 addresses
+// near its mapped form (::fffe:102:304 is 2 bits from 1.2.3.4). Two IPv4 addresses
+// differ on at most 32 bits.
+type IPAddr netip.Addr
+
+func (ip IPAddr) DistanceFrom(other MetricTensor) Distance {
+	x, y := netip.Addr(ip), netip.Addr(other.(IPAddr))
+	a, b := x.As16(), y.As16()
+	hi := binary.BigEndian.Uint64(a[:8]) ^ binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(a[8:]) ^ binary.BigEndian.Uint64(b[8:])
+	dist := Distance(bits.OnesCount64(hi) + bits.OnesCount64(lo))
+	if x.Is4() != y.Is4() {
+		dist++
+	}
+	return dist
+}
+
+func (ip IPAddr) ToString() string {
+	return netip.Addr(ip).String()
+}
@@ -0,0 +1,48 @@
+package go_bk_tree
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func ipAddr(s string) IPAddr {
+	return IPAddr(netip.MustParseAddr(s))
+}
+
+func TestIPAddr_DistanceFrom(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected Distance
+	}{
+		{"10.0.0.1", "10.0.0.1", 0},
+		{"10.0.0.1", "10.0.0.3", 1},
+		{"10.0.0.0", "11.0.0.255", 9},
+		{"::ffff:10.0.0.1", "10.0.0.1", 1},
+		{"::ffff:10.0.0.1", "::ffff:10.0.0.3", 1},
+		{"10.0.0.1", "::a00:1", 17},
+		{"::fffe:102:304", "1.2.3.4", 2},
+		{"2001:db8::1", "2001:db8::2", 2},
+	}
+	for _, c := range cases {
+		if d := ipAddr(c.a).DistanceFrom(ipAddr(c.b)); d != c.expected {
+			t.Errorf("%s - %s: expected: %d, got: %d", c.a, c.b, c.expected, d)
+		}
+	}
+	if s := ipAddr("10.0.0.1").ToString(); s != "10.0.0.1" {
+		t.Errorf("expected: %s, got: %s", "10.0.0.1", s)
+	}
+}
+
+func TestBKTree_Search_IPAddr(t *testing.T) {
+	tree := new(BKTree)
+	for _, s := range []string{"192.168.1.1", "192.168.1.2", "10.0.0.1", "2001:db8::1"} {
+		tree.Add(ipAddr(s))
+	}
+	if tree.Add(ipAddr("::ffff:10.0.0.1")); tree.Size != 5 {
+		t.Errorf("expected the IPv4-mapped address not to be a duplicate, got size %d", tree.Size)
+	}
+	results, _ := tree.Search(ipAddr("192.168.1.3"), 1)
+	if got := sortedStrings(results); len(got) != 2 || got[0] != "192.168.1.1" || got[1] != "192.168.1.2" {
+		t.Errorf("expected the two 192.168.1.x addresses, got: %v", got)
+	}
+}
@@ -0,0 +1,98 @@
+package go_bk_tree
+
+import "iter"
+
+// All returns an iterator over every value indexed in the tree
+func (tree *BKTree) All() iter.Seq[MetricTensor] {
+	return func(yield func(MetricTensor) bool) {
+		if tree.Root == nil {
+			return
+		}
+		stack := []*BkTreeNode{tree.Root}
+		for len(stack) > 0 {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if node.MetricTensor != nil && !yield(node.MetricTensor) {
+				return
+			}
+			for _, child := range node.Children {
+				stack = append(stack, child)
+			}
+		}
+	}
+}
+
+// Matches returns an iterator over the values within radius of val together with
+// their distance from val, at most MaxResults of them. The tree is traversed lazily,
+// so breaking out of the loop stops the search.
+func (tree *BKTree) Matches(val MetricTensor, radius Distance) iter.Seq2[MetricTensor, Distance] {
+	return func(yield func(MetricTensor, Distance) bool) {
+		if tree.Root == nil || val == nil {
+			return
+		}
+		val := tree.normalize(val)
+		candidates := []*BkTreeNode{tree.Root}
+		n := 0
+		for len(candidates) > 0 {
+			cand := candidates[0]
+			candidates = candidates[1:]
+			if cand.MetricTensor == nil {
+				candidates = appendChildren(candidates, cand)
+				continue
+			}
+			dist := cand.DistanceFrom(val)
+			if dist <= radius {
+				n++
+				if !yield(cand.MetricTensor, dist) || tree.capped(n) {
+					return
+				}
+			}
+			low, high := searchWindow(dist, radius)
+			for d, child := range cand.Children {
+				if d >= low && d <= high {
+					candidates = append(candidates, child)
+				}
+			}
+		}
+	}
+}
+
+// Stream works like Matches through the same traversal as Search: nil values are
+// skipped and at most MaxResults values are yielded. Breaking out of the loop stops the
+// traversal, no distance is computed afterwards, and it holds no goroutine to clean up.
+func (tree *BKTree) Stream(val MetricTensor, radius Distance) iter.Seq2[MetricTensor, Distance] {
+	return func(yield func(MetricTensor, Distance) bool) {
+		n := 0
+		tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+			if dist > radius {
+				return true
+			}
+			n++
+			return yield(node.MetricTensor, dist) && !tree.capped(n)
+		})
+	}
+}
+
+// Levels returns the values grouped by their depth, the root alone being the first
+// level. Within a level values are ordered by their parent's position in the previous
+// level, then by bucket distance, so the result is deterministic.
+func (tree *BKTree) Levels() [][]MetricTensor {
+	levels := make([][]MetricTensor, 0)
+	if tree.Root == nil {
+		return levels
+	}
+	level := []*BkTreeNode{tree.Root}
+	for len(level) > 0 {
+		values := make([]MetricTensor, len(level))
+		next := make([]*BkTreeNode, 0, len(level))
+		for i, node := range level {
+			values[i] = node.MetricTensor
+			for _, dist := range node.sortedBuckets() {
+				next = append(next, node.Children[dist])
+			}
+		}
+		levels = append(levels, values)
+		level = next
+	}
+	return levels
+}
@@ -0,0 +1,89 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBKTree_All(t *testing.T) {
+	wordsList := []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"}
+	tree := createNewTreeFromWords(wordsList)
+	var got []MetricTensor
+	for v := range tree.All() {
+		got = append(got, v)
+	}
+	var want []MetricTensor
+	for _, w := range wordsList {
+		want = append(want, Word(w))
+	}
+	if !reflect.DeepEqual(sortedStrings(got), sortedStrings(want)) || tree.Size != len(got) {
+		t.Errorf("expected: %v, got: %v", sortedStrings(want), sortedStrings(got))
+	}
+	for range new(BKTree).All() {
+		t.Error("expected no values from an empty tree")
+	}
+}
+
+func TestBKTree_Matches(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	var got []MetricTensor
+	for v, dist := range tree.Matches(Word("sort"), 2) {
+		if d := v.DistanceFrom(Word("sort")); d != dist {
+			t.Errorf("expected: %d, got: %d", d, dist)
+		}
+		got = append(got, v)
+	}
+	if expected := []string{"soft", "sorted"}; !reflect.DeepEqual(sortedStrings(got), expected) {
+		t.Errorf("expected: %v, got: %v", expected, sortedStrings(got))
+	}
+	n := 0
+	for range tree.Matches(Word("sort"), 100) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("expected: %d, got: %d", 1, n)
+	}
+}
+
+func TestBKTree_Stream(t *testing.T) {
+	calls := 0
+	word := func(w string) countingWord { return countingWord{Word(w), &calls} }
+	tree := new(BKTree)
+	for _, w := range []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"} {
+		tree.Add(word(w))
+	}
+	var got []MetricTensor
+	for v := range tree.Stream(word("sort"), 2) {
+		got = append(got, v.(countingWord).Word)
+	}
+	if expected := []string{"soft", "sorted"}; !reflect.DeepEqual(sortedStrings(got), expected) {
+		t.Errorf("expected: %v, got: %v", expected, sortedStrings(got))
+	}
+	calls = 0
+	for range tree.Stream(word("some"), 100) {
+		break
+	}
+	if calls != 1 {
+		t.Errorf("expected the traversal to stop after the first match, got %d computations", calls)
+	}
+	tree.MaxResults = 3
+	n := 0
+	for range tree.Stream(word("some"), 100) {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("expected: %d, got: %d", 3, n)
+	}
+}
+
+func TestBKTree_Levels(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"a", "ab", "abc", "d"})
+	expected := [][]MetricTensor{{Word("a")}, {Word("ab"), Word("abc")}, {Word("d")}}
+	if got := tree.Levels(); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+	if got := new(BKTree).Levels(); len(got) != 0 {
+		t.Errorf("expected no levels, got: %v", got)
+	}
+}
@@ -0,0 +1,72 @@
+package go_bk_tree
+
+import (
+	"sort"
+	"strings"
+)
+
+// JaccardScale is the factor the Jaccard distance of two ShingleSets is multiplied by
+// before being rounded to a Distance
+const JaccardScale = 1000
+
+// ShingleSet is a built-in MetricTensor for sets of strings (e.g. the shingles of a
+// document, see Shingles), the distance between two sets is their Jaccard distance
+// 1 - |A ∩ B| / |A ∪ B| scaled by JaccardScale. The strings are kept sorted and
+// unique, so build values with NewShingleSet. Two empty sets are 0 apart.
+//
+// Jaccard distance is a metric, but rounding can break the triangle inequality by
+// 1, search with a radius 1 larger than needed when every match matters.
+type ShingleSet []string
+
+// NewShingleSet returns the set of the given strings, sorted and without duplicates
+func NewShingleSet(members ...string) ShingleSet {
+	set := make(ShingleSet, len(members))
+	copy(set, members)
+	sort.Strings(set)
+	unique := set[:0]
+	for i, m := range set {
+		if i == 0 || m != set[i-1] {
+			unique = append(unique, m)
+		}
+	}
+	return unique
+}
+
+// Shingles returns the set of the runs of k consecutive words of text
+func Shingles(text string, k int) ShingleSet {
+	words := strings.Fields(text)
+	if k <= 0 || len(words) < k {
+		return NewShingleSet()
+	}
+	shingles := make([]string, 0, len(words)-k+1)
+	for i := 0; i+k <= len(words); i++ {
+		shingles = append(shingles, strings.Join(words[i:i+k], " "))
+	}
+	return NewShingleSet(shingles...)
+}
+
+func (s ShingleSet) DistanceFrom(other MetricTensor) Distance {
+	o := other.(ShingleSet)
+	inter := 0
+	for i, j := 0, 0; i < len(s) && j < len(o); {
+		switch {
+		case s[i] < o[j]:
+			i++
+		case s[i] > o[j]:
+			j++
+		default:
+			inter++
+			i++
+			j++
+		}
+	}
+	union := len(s) + len(o) - inter
+	if union == 0 {
+		return 0
+	}
+	return Distance((JaccardScale*(union-inter) + union/2) / union)
+}
+
+func (s ShingleSet) ToString() string {
+	return strings.Join(s, "|")
+}
@@ -0,0 +1,47 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShingleSet_DistanceFrom(t *testing.T) {
+	a := NewShingleSet("b", "a", "c", "a")
+	if expected := (ShingleSet{"a", "b", "c"}); !reflect.DeepEqual(a, expected) {
+		t.Errorf("expected: %v, got: %v", expected, a)
+	}
+	cases := []struct {
+		other    ShingleSet
+		expected Distance
+	}{
+		{NewShingleSet("a", "b", "c"), 0},
+		{NewShingleSet("a", "b", "d"), JaccardScale / 2},
+		{NewShingleSet("d"), JaccardScale},
+		{NewShingleSet("a", "b"), 333},
+	}
+	for _, c := range cases {
+		if d := a.DistanceFrom(c.other); d != c.expected {
+			t.Errorf("%v: expected: %d, got: %d", c.other, c.expected, d)
+		}
+	}
+	if d := NewShingleSet().DistanceFrom(NewShingleSet()); d != 0 {
+		t.Errorf("expected: %d, got: %d", 0, d)
+	}
+}
+
+func TestBKTree_Search_Shingles(t *testing.T) {
+	docs := []string{
+		"the quick brown fox jumps over the lazy dog",
+		"the quick brown fox jumped over the lazy dog",
+		"a completely different sentence about cats",
+		"the quick brown fox jumps over the lazy cat",
+	}
+	tree := new(BKTree)
+	for _, d := range docs {
+		tree.Add(Shingles(d, 2))
+	}
+	results, _ := tree.Search(Shingles(docs[0], 2), JaccardScale/2)
+	if len(results) != 3 {
+		t.Errorf("expected the 3 fox sentences, got: %v", results)
+	}
+}
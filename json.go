@@ -0,0 +1,213 @@
+package go_bk_tree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// DecodeOptions configures FromJsonWithOptions
+type DecodeOptions struct {
+	// Strict recomputes the distance between every decoded child and its parent and
+	// aborts on the first one that does not match its bucket, or that sits in a bucket
+	// Add never fills (0 or below), so that a corrupt or untrusted input never yields a
+	// tree returning wrong search results. It costs one distance computation per node.
+	Strict bool
+	// MaxNodes, when positive, aborts the decoding with ErrTooManyNodes as soon as the
+	// input holds more than MaxNodes nodes, which bounds the memory taken by an untrusted
+	// input beyond its own size (read it through an io.LimitReader to bound that too).
+	// Defaults to 0, unlimited.
+	MaxNodes int
+}
+
+// ErrTooManyNodes is returned by FromJsonWithOptions and FromObjectJsonWithOptions when
+// the input exceeds MaxNodes
+var ErrTooManyNodes = errors.New("go_bk_tree: too many nodes")
+
+// FormatDistance returns the encoding of a child bucket as a JSON object key, used by
+// both serialized forms: the shortest base-10 form of the integer, with a leading '-'
+// for negative values and no '+' sign, leading zeros or spaces (e.g. "0", "12", "-3").
+// Every Distance has exactly one encoding, so keys can be compared as strings by
+// consumers in other languages and a round-trip always yields the same buckets.
+func FormatDistance(d Distance) string {
+	return strconv.FormatInt(int64(d), 10)
+}
+
+// ParseDistance decodes a child bucket key written by FormatDistance, rejecting any
+// other spelling of a number (e.g. "+1", "01" or "1.0")
+func ParseDistance(s string) (Distance, error) {
+	d, err := strconv.ParseInt(s, 10, strconv.IntSize)
+	if err != nil || FormatDistance(Distance(d)) != s {
+		return 0, fmt.Errorf("go_bk_tree: invalid distance key %q", s)
+	}
+	return Distance(d), nil
+}
+
+// FromJson rebuilds a tree serialized by ToJson, factory turns the ToString
+// form of a value back into a MetricTensor
+func FromJson(data []byte, factory func(string) MetricTensor) (*BKTree, error) {
+	return FromJsonWithOptions(data, factory, DecodeOptions{})
+}
+
+// FromJsonWithOptions works like FromJson, see DecodeOptions
+func FromJsonWithOptions(data []byte, factory func(string) MetricTensor, opts DecodeOptions) (*BKTree, error) {
+	tree := new(BKTree)
+	if string(data) == "null" {
+		return tree, nil
+	}
+	nodes := 0
+	root, err := decodeArrayNode(data, factory, opts, nil, &nodes)
+	if err != nil {
+		return nil, err
+	}
+	tree.Root = root
+	tree.CalculateSize()
+	return tree, nil
+}
+
+// decodeArrayNode decodes the [value, {bucket: child}] form written by BkTreeNode.MarshalJSON,
+// nodes counts the nodes decoded so far
+func decodeArrayNode(data []byte, factory func(string) MetricTensor, opts DecodeOptions, path []ancestor, nodes *int) (*BkTreeNode, error) {
+	*nodes++
+	if opts.MaxNodes > 0 && *nodes > opts.MaxNodes {
+		return nil, fmt.Errorf("%w: more than %d at %s", ErrTooManyNodes, opts.MaxNodes, formatPath(path))
+	}
+	var array []json.RawMessage
+	if err := ffjson.Unmarshal(data, &array); err != nil {
+		return nil, err
+	}
+	if len(array) != 2 {
+		return nil, fmt.Errorf("go_bk_tree: expected a [value, children] pair at %s", formatPath(path))
+	}
+	var value string
+	if err := ffjson.Unmarshal(array[0], &value); err != nil {
+		return nil, err
+	}
+	var children map[string]json.RawMessage
+	if err := ffjson.Unmarshal(array[1], &children); err != nil {
+		return nil, err
+	}
+	if opts.MaxNodes > 0 && *nodes+len(children) > opts.MaxNodes {
+		// fail before decoding the values of a node claiming too many children
+		return nil, fmt.Errorf("%w: more than %d at %s", ErrTooManyNodes, opts.MaxNodes, formatPath(path))
+	}
+	node := newbkTreeNode(factory(value))
+	if node.MetricTensor == nil {
+		return nil, fmt.Errorf("%w: %q at %s", ErrNilValue, value, formatPath(path))
+	}
+	if len(path) > 0 && opts.Strict {
+		parent := path[len(path)-1]
+		if dist := parent.node.DistanceFrom(node.MetricTensor); dist != parent.bucket {
+			return nil, fmt.Errorf("%w: %s at %s is %d away from its parent %s",
+				ErrInvalidTree, value, formatPath(path), dist, parent.node.ToString())
+		}
+	}
+	for key, raw := range children {
+		dist, err := ParseDistance(key)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Strict && dist <= 0 {
+			return nil, fmt.Errorf("%w: bucket %s at %s", ErrInvalidTree, key, formatPath(path))
+		}
+		child, err := decodeArrayNode(raw, factory, opts, append(path, ancestor{node, dist}), nodes)
+		if err != nil {
+			return nil, err
+		}
+		node.Children[dist] = child
+	}
+	return node, nil
+}
+
+// objectNode is the object-based JSON encoding of a node:
+//
+//	{"value": "some", "children": {"2": {"value": "same", "children": {}}}}
+type objectNode struct {
+	Value    string                 `json:"value"`
+	Children map[string]*objectNode `json:"children"`
+}
+
+func newObjectNode(node *BkTreeNode) *objectNode {
+	obj := &objectNode{
+		Value:    node.ToString(),
+		Children: make(map[string]*objectNode, len(node.Children)),
+	}
+	for dist, child := range node.Children {
+		obj.Children[FormatDistance(dist)] = newObjectNode(child)
+	}
+	return obj
+}
+
+// ToObjectJson serializes the tree as nested {"value", "children"} objects, which is
+// easier to consume from other languages than the compact array form of ToJson
+func (tree *BKTree) ToObjectJson() ([]byte, error) {
+	if tree.Root == nil {
+		return ffjson.Marshal(nil)
+	}
+	return ffjson.Marshal(newObjectNode(tree.Root))
+}
+
+// FromObjectJson rebuilds a tree serialized by ToObjectJson, factory turns the
+// ToString form of a value back into a MetricTensor
+func FromObjectJson(data []byte, factory func(string) MetricTensor) (*BKTree, error) {
+	return FromObjectJsonWithOptions(data, factory, DecodeOptions{})
+}
+
+// FromObjectJsonWithOptions works like FromObjectJson, see DecodeOptions. The nodes
+// are counted once the whole input is parsed, so MaxNodes bounds the size of the tree
+// but not the memory taken by the parsing.
+func FromObjectJsonWithOptions(data []byte, factory func(string) MetricTensor, opts DecodeOptions) (*BKTree, error) {
+	var root *objectNode
+	if err := ffjson.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	tree := new(BKTree)
+	if root == nil {
+		return tree, nil
+	}
+	nodes := 0
+	var err error
+	if tree.Root, err = root.toNode(factory, opts, nil, &nodes); err != nil {
+		return nil, err
+	}
+	tree.CalculateSize()
+	return tree, nil
+}
+
+// toNode decodes the node like decodeArrayNode, nodes counts the nodes decoded so far
+func (obj *objectNode) toNode(factory func(string) MetricTensor, opts DecodeOptions, path []ancestor, nodes *int) (*BkTreeNode, error) {
+	if obj == nil {
+		return nil, fmt.Errorf("go_bk_tree: expected a {value, children} object at %s", formatPath(path))
+	}
+	*nodes++
+	if opts.MaxNodes > 0 && *nodes+len(obj.Children) > opts.MaxNodes {
+		return nil, fmt.Errorf("%w: more than %d at %s", ErrTooManyNodes, opts.MaxNodes, formatPath(path))
+	}
+	node := newbkTreeNode(factory(obj.Value))
+	if node.MetricTensor == nil {
+		return nil, fmt.Errorf("%w: %q at %s", ErrNilValue, obj.Value, formatPath(path))
+	}
+	if len(path) > 0 && opts.Strict {
+		parent := path[len(path)-1]
+		if dist := parent.node.DistanceFrom(node.MetricTensor); dist != parent.bucket {
+			return nil, fmt.Errorf("%w: %s at %s is %d away from its parent %s",
+				ErrInvalidTree, obj.Value, formatPath(path), dist, parent.node.ToString())
+		}
+	}
+	for key, child := range obj.Children {
+		dist, err := ParseDistance(key)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Strict && dist <= 0 {
+			return nil, fmt.Errorf("%w: bucket %s at %s", ErrInvalidTree, key, formatPath(path))
+		}
+		if node.Children[dist], err = child.toNode(factory, opts, append(path, ancestor{node, dist}), nodes); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
@@ -0,0 +1,170 @@
+package go_bk_tree
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func wordFactory(s string) MetricTensor {
+	return Word(s)
+}
+
+func TestBKTree_ToObjectJson(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"a", "ab", "abc", "d"})
+	data, err := tree.ToObjectJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"value":"a","children":{"1":{"value":"ab","children":{}},"2":{"value":"abc","children":{"4":{"value":"d","children":{}}}}}}`
+	if string(data) != expected {
+		t.Errorf("expected: %s, got: %s", expected, data)
+	}
+	decoded, err := FromObjectJson(data, wordFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Size != tree.Size {
+		t.Errorf("expected: %d, got: %d", tree.Size, decoded.Size)
+	}
+	expectedJson, _ := tree.ToJson()
+	decodedJson, _ := decoded.ToJson()
+	if !bytes.Equal(expectedJson, decodedJson) {
+		t.Errorf("expected: %s, got: %s", expectedJson, decodedJson)
+	}
+	if _, err := FromObjectJson([]byte(`{"value": 1}`), wordFactory); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestFromJsonWithOptions(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	data, _ := tree.ToJson()
+	decoded, err := FromJsonWithOptions(data, wordFactory, DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Size != tree.Size {
+		t.Errorf("expected: %d, got: %d", tree.Size, decoded.Size)
+	}
+	if err := decoded.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	corrupt := []byte(`["some",{"1":["somes",{"9":["mole",{}]}],"2":["same",{}]}]`)
+	if _, err := FromJson(corrupt, wordFactory); err != nil {
+		t.Errorf("expected lenient decoding to succeed, got: %v", err)
+	}
+	_, err = FromJsonWithOptions(corrupt, wordFactory, DecodeOptions{Strict: true})
+	if !errors.Is(err, ErrInvalidTree) {
+		t.Fatalf("expected: %v, got: %v", ErrInvalidTree, err)
+	}
+	if !strings.Contains(err.Error(), "root/1/9") {
+		t.Errorf("expected the node path in %q", err)
+	}
+}
+
+func TestFromJsonWithOptions_NonPositiveBucket(t *testing.T) {
+	for _, c := range []struct {
+		array, object string
+	}{
+		{`["some",{"0":["some",{}]}]`, `{"value": "some", "children": {"0": {"value": "some"}}}`},
+		{`["some",{"-3":["soft",{}]}]`, `{"value": "some", "children": {"-3": {"value": "soft"}}}`},
+	} {
+		if _, err := FromJson([]byte(c.array), wordFactory); err != nil {
+			t.Errorf("%s: expected lenient decoding to succeed, got: %v", c.array, err)
+		}
+		if _, err := FromJsonWithOptions([]byte(c.array), wordFactory, DecodeOptions{Strict: true}); !errors.Is(err, ErrInvalidTree) {
+			t.Errorf("%s: expected: %v, got: %v", c.array, ErrInvalidTree, err)
+		}
+		if _, err := FromObjectJsonWithOptions([]byte(c.object), wordFactory, DecodeOptions{Strict: true}); !errors.Is(err, ErrInvalidTree) {
+			t.Errorf("%s: expected: %v, got: %v", c.object, ErrInvalidTree, err)
+		}
+	}
+}
+
+func TestFromJsonWithOptions_MaxNodes(t *testing.T) {
+	data, _ := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"}).ToJson()
+	if tree, err := FromJsonWithOptions(data, wordFactory, DecodeOptions{MaxNodes: 7}); err != nil || tree.Size != 7 {
+		t.Errorf("expected a tree of 7 values within budget, got: %v", err)
+	}
+	if _, err := FromJsonWithOptions(data, wordFactory, DecodeOptions{MaxNodes: 6}); !errors.Is(err, ErrTooManyNodes) {
+		t.Errorf("expected: %v, got: %v", ErrTooManyNodes, err)
+	}
+	// a deep chain exceeding the budget fails without decoding all of it
+	var chain strings.Builder
+	for i := 0; i < 100; i++ {
+		chain.WriteString(`["w",{"1":`)
+	}
+	chain.WriteString(`["w",{}]`)
+	chain.WriteString(strings.Repeat("}]", 100))
+	calls := 0
+	factory := func(s string) MetricTensor {
+		calls++
+		return Word(s)
+	}
+	if _, err := FromJsonWithOptions([]byte(chain.String()), factory, DecodeOptions{MaxNodes: 10}); !errors.Is(err, ErrTooManyNodes) {
+		t.Errorf("expected: %v, got: %v", ErrTooManyNodes, err)
+	}
+	if calls > 10 {
+		t.Errorf("expected at most 10 values to be decoded, got: %d", calls)
+	}
+}
+
+func TestFromJson_NilValue(t *testing.T) {
+	data, _ := createNewTreeFromWords([]string{"some", "soft"}).ToJson()
+	factory := func(s string) MetricTensor {
+		if s == "soft" {
+			return nil
+		}
+		return Word(s)
+	}
+	if _, err := FromJson(data, factory); !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected: %v, got: %v", ErrNilValue, err)
+	}
+}
+
+func TestParseDistance(t *testing.T) {
+	for _, d := range []Distance{0, 1, 12, -3, maxDistance, minDistance} {
+		if got, err := ParseDistance(FormatDistance(d)); err != nil || got != d {
+			t.Errorf("%d: got: %d, %v", d, got, err)
+		}
+	}
+	for _, s := range []string{"", "+1", "01", "-0", "1.0", " 1", "1e2"} {
+		if _, err := ParseDistance(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+	if _, err := FromJson([]byte(`["some", {"02": ["soft", {}]}]`), wordFactory); err == nil {
+		t.Error("expected a non-canonical key to be rejected")
+	}
+	if _, err := FromObjectJson([]byte(`{"value": "some", "children": {"+2": {"value": "soft", "children": {}}}}`), wordFactory); err == nil {
+		t.Error("expected a non-canonical key to be rejected")
+	}
+}
+
+func TestFromObjectJson_Malformed(t *testing.T) {
+	if _, err := FromObjectJson([]byte(`{"value": "some", "children": {"4": null}}`), wordFactory); err == nil {
+		t.Error("expected a null child to be rejected")
+	}
+	factory := func(s string) MetricTensor {
+		if s == "bad" {
+			return nil
+		}
+		return Word(s)
+	}
+	if _, err := FromObjectJson([]byte(`{"value": "some", "children": {"4": {"value": "bad"}}}`), factory); !errors.Is(err, ErrNilValue) {
+		t.Errorf("expected: %v, got: %v", ErrNilValue, err)
+	}
+	data, _ := createNewTreeFromWords([]string{"some", "soft", "same", "sole"}).ToObjectJson()
+	if _, err := FromObjectJsonWithOptions(data, wordFactory, DecodeOptions{MaxNodes: 3}); !errors.Is(err, ErrTooManyNodes) {
+		t.Errorf("expected: %v, got: %v", ErrTooManyNodes, err)
+	}
+	if tree, err := FromObjectJsonWithOptions(data, wordFactory, DecodeOptions{MaxNodes: 4, Strict: true}); err != nil || tree.Size != 4 {
+		t.Errorf("expected 4 values, got: %v", err)
+	}
+	if _, err := FromObjectJsonWithOptions([]byte(`{"value": "some", "children": {"3": {"value": "soft"}}}`), wordFactory, DecodeOptions{Strict: true}); !errors.Is(err, ErrInvalidTree) {
+		t.Errorf("expected: %v, got: %v", ErrInvalidTree, err)
+	}
+}
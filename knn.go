@@ -0,0 +1,276 @@
+package go_bk_tree
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Match is a value found by a search together with its distance from the query
+type Match struct {
+	Value    MetricTensor
+	Distance Distance
+}
+
+// ScoredNode is a subtree waiting to be explored by a best-first search, Bound is a
+// lower bound of the distance between the query and any value of the subtree
+type ScoredNode struct {
+	Node  *BkTreeNode
+	Bound Distance
+}
+
+// NodeHeap is a min-heap of ScoredNodes ordered by Bound, meant to be driven with
+// container/heap. It is the frontier of SearchKNN and can be reused to write other
+// best-first searches, for a child at bucket d of a node dist away from the query a
+// valid bound is |dist - d| (see ExampleNodeHeap).
+type NodeHeap []ScoredNode
+
+func (h NodeHeap) Len() int            { return len(h) }
+func (h NodeHeap) Less(i, j int) bool  { return h[i].Bound < h[j].Bound }
+func (h NodeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *NodeHeap) Push(x interface{}) { *h = append(*h, x.(ScoredNode)) }
+func (h *NodeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// SearchKNN returns the k values closest to val sorted by ascending distance, and the
+// number of distance computations. Subtrees are explored best-first (by the lowest
+// distance they could contain) and the search stops once none can beat the k-th match.
+func (tree *BKTree) SearchKNN(val MetricTensor, k int) ([]Match, int) {
+	if tree.Root == nil {
+		return make([]Match, 0), 0
+	}
+	if tree.capped(k) {
+		k = tree.MaxResults
+	}
+	return searchKNN([]*BkTreeNode{tree.Root}, tree.normalize(val), k)
+}
+
+// SearchKNNProgressive finds the same k nearest values as SearchKNN in stages, for
+// interactive use: it searches within initialRadius, then doubles the radius until
+// k values are found or the whole tree is within the radius. After every stage progress
+// gets the best matches so far (sorted, at most k, owned by the callee), which are
+// exactly the k nearest among the values within the stage radius. The last call
+// holds the final result, which is also returned with the number of distance
+// computations. Distances are memoized across stages, but every stage traverses the
+// tree again within its radius: with a good initialRadius the first results come
+// fast, with a poor one the total cost exceeds a single SearchKNN by up to
+// O(log(max distance)) traversals.
+func (tree *BKTree) SearchKNNProgressive(val MetricTensor, k int, initialRadius Distance, progress func([]Match)) ([]Match, int) {
+	if tree.Root == nil || val == nil || k <= 0 {
+		return make([]Match, 0), 0
+	}
+	if tree.capped(k) {
+		k = tree.MaxResults
+	}
+	val = tree.normalize(val)
+	known := make(map[*BkTreeNode]Distance)
+	radius := max(initialRadius, 0)
+	for {
+		results := make([]Match, 0, k)
+		// missed reports a value out of the radius, visited or pruned
+		missed := false
+		candidates := []*BkTreeNode{tree.Root}
+		for len(candidates) > 0 {
+			cand := candidates[len(candidates)-1]
+			candidates = candidates[:len(candidates)-1]
+			if cand.MetricTensor == nil {
+				candidates = appendChildren(candidates, cand)
+				continue
+			}
+			dist, ok := known[cand]
+			if !ok {
+				dist = cand.DistanceFrom(val)
+				known[cand] = dist
+			}
+			if dist <= radius {
+				results = insertMatch(results, Match{cand.MetricTensor, dist}, k)
+			} else {
+				missed = true
+			}
+			low, high := searchWindow(dist, radius)
+			for d, child := range cand.Children {
+				if d >= low && d <= high {
+					candidates = append(candidates, child)
+				} else {
+					missed = true
+				}
+			}
+		}
+		if progress != nil {
+			progress(append([]Match(nil), results...))
+		}
+		if len(results) == k || !missed || radius == maxDistance {
+			return results, len(known)
+		}
+		radius = addDistance(addDistance(radius, radius), 1)
+	}
+}
+
+// NearestJoin returns, for every value of a, its nearest value in b with their
+// distance, index-aligned with a. The Match of a value is zero if b is empty.
+func NearestJoin(a []MetricTensor, b *BKTree) []Match {
+	matches := make([]Match, len(a))
+	for i, val := range a {
+		matches[i] = b.nearest(val)
+	}
+	return matches
+}
+
+// AllPairsWithin returns every pair of values at most t apart, e.g. to cluster the
+// near-duplicates of a dataset. Each pair is reported once, ordered as a depth-first
+// walk of the tree meets them (an ancestor before its descendants), and a value is
+// never paired with itself. It runs one search for radius t per value, which prunes
+// most of the n^2 comparisons for a small t.
+func (tree *BKTree) AllPairsWithin(t Distance) [][2]MetricTensor {
+	pairs := make([][2]MetricTensor, 0)
+	if tree.Root == nil {
+		return pairs
+	}
+	nodes := collectNodes(tree.Root, nil)
+	index := make(map[*BkTreeNode]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+	for i, node := range nodes {
+		if node.MetricTensor == nil {
+			continue
+		}
+		tree.traverse(node.MetricTensor, t, func(other *BkTreeNode, dist Distance) bool {
+			if dist <= t && index[other] > i {
+				pairs = append(pairs, [2]MetricTensor{node.MetricTensor, other.MetricTensor})
+			}
+			return true
+		})
+	}
+	return pairs
+}
+
+// NearestJoinParallel works like NearestJoin but splits a between at most NumCPU
+// goroutines, b must not be modified until it returns
+func NearestJoinParallel(a []MetricTensor, b *BKTree) []Match {
+	matches := make([]Match, len(a))
+	chunk := (len(a) + numCPU - 1) / numCPU
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(a); lo += chunk {
+		hi := min(lo+chunk, len(a))
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				matches[i] = b.nearest(a[i])
+			}
+		}(lo, hi)
+	}
+	wg.Wait()
+	return matches
+}
+
+// nearest returns the value closest to val, or a zero Match if the tree is empty
+func (tree *BKTree) nearest(val MetricTensor) Match {
+	if knn, _ := tree.SearchKNN(val, 1); len(knn) > 0 {
+		return knn[0]
+	}
+	return Match{}
+}
+
+// ID identifies an indexed item: the Key of a Keyed value, the ToString of any other
+// value (of its Original for a Normalized value), see IDOf
+type ID = string
+
+// IDOf returns the ID of val
+func IDOf(val MetricTensor) ID {
+	switch v := val.(type) {
+	case Keyed:
+		return v.Key
+	case Normalized:
+		return IDOf(v.Original)
+	}
+	return val.ToString()
+}
+
+// NearestExcluding returns the value closest to val whose ID (see IDOf) is not in
+// excluded, with its distance, or false if every value is excluded, e.g. to recommend
+// the best item not shown yet. Excluded values are skipped during the KNN search
+// itself, which keeps exploring until a value that is not excluded is found: it costs
+// about as much as a SearchKNN for 1 + the number of excluded values nearer than the
+// answer, with no over-fetching to filter client-side.
+func (tree *BKTree) NearestExcluding(val MetricTensor, excluded map[ID]bool) (MetricTensor, Distance, bool) {
+	if tree.Root == nil {
+		return nil, 0, false
+	}
+	keep := func(v MetricTensor) bool { return !excluded[IDOf(v)] }
+	knn, _ := searchKNNWhere([]*BkTreeNode{tree.Root}, tree.normalize(val), 1, keep)
+	if len(knn) == 0 {
+		return nil, 0, false
+	}
+	return knn[0].Value, knn[0].Distance, true
+}
+
+// searchKNN runs a best-first KNN search over several trees at once
+func searchKNN(roots []*BkTreeNode, val MetricTensor, k int) ([]Match, int) {
+	return searchKNNWhere(roots, val, k, nil)
+}
+
+// searchKNNWhere works like searchKNN, only collecting the values keep accepts (all
+// of them if keep is nil). Rejected values still bound the search of their children.
+func searchKNNWhere(roots []*BkTreeNode, val MetricTensor, k int, keep func(MetricTensor) bool) ([]Match, int) {
+	count := 0
+	results := make([]Match, 0, k)
+	if k <= 0 || val == nil {
+		return results, count
+	}
+	frontier := make(NodeHeap, 0, len(roots))
+	for _, root := range roots {
+		frontier = append(frontier, ScoredNode{root, 0})
+	}
+	heap.Init(&frontier)
+	for frontier.Len() > 0 {
+		cand := heap.Pop(&frontier).(ScoredNode)
+		if len(results) == k && cand.Bound > results[k-1].Distance {
+			break
+		}
+		if cand.Node.MetricTensor == nil {
+			// a nil value gives no bound, its children keep the one of the node
+			for _, child := range cand.Node.Children {
+				heap.Push(&frontier, ScoredNode{child, cand.Bound})
+			}
+			continue
+		}
+		dist := cand.Node.DistanceFrom(val)
+		count += 1
+		if (len(results) < k || dist < results[len(results)-1].Distance) && (keep == nil || keep(cand.Node.MetricTensor)) {
+			results = insertMatch(results, Match{cand.Node.MetricTensor, dist}, k)
+		}
+		for d, child := range cand.Node.Children {
+			bound := diffDistance(dist, d)
+			if bound < cand.Bound {
+				bound = cand.Bound
+			}
+			if len(results) == k && bound > results[k-1].Distance {
+				continue
+			}
+			heap.Push(&frontier, ScoredNode{child, bound})
+		}
+	}
+	return results, count
+}
+
+// insertMatch inserts m into the sorted results, keeping at most k of them
+func insertMatch(results []Match, m Match, k int) []Match {
+	i := len(results)
+	for i > 0 && results[i-1].Distance > m.Distance {
+		i--
+	}
+	if len(results) < k {
+		results = append(results, Match{})
+	} else if i == len(results) {
+		return results
+	}
+	copy(results[i+1:], results[i:])
+	results[i] = m
+	return results
+}
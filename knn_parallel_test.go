@@ -0,0 +1,63 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBKTree_SearchKNNParallel(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 9)
+	goroutines := runtime.NumGoroutine()
+	for i, q := range hashes[:30] {
+		q ^= Hamming64(0xf0f << (i % 50))
+		for _, k := range []int{1, 10, 100} {
+			expected, _ := tree.SearchKNN(q, k)
+			for _, workers := range []int{1, 4, 16} {
+				got, count := tree.SearchKNNParallel(q, k, workers)
+				if !reflect.DeepEqual(matchDistances(expected), matchDistances(got)) {
+					t.Fatalf("query %d, k %d, %d workers: expected: %v, got: %v", i, k, workers, matchDistances(expected), matchDistances(got))
+				}
+				if count == 0 || count > tree.Size {
+					t.Errorf("unexpected count: %d", count)
+				}
+			}
+		}
+	}
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("expected the workers to exit, got %d goroutines instead of %d", n, goroutines)
+	}
+	if got, _ := new(BKTree).SearchKNNParallel(hashes[0], 3, 4); len(got) != 0 {
+		t.Errorf("expected no match, got: %v", got)
+	}
+}
+
+// slowHash is a Hamming64 whose distance takes 1ms without using the CPU, like a
+// metric calling out to another process
+type slowHash struct{ Hamming64 }
+
+func (h slowHash) DistanceFrom(other MetricTensor) Distance {
+	time.Sleep(time.Millisecond)
+	return h.Hamming64.DistanceFrom(other.(slowHash).Hamming64)
+}
+
+func benchmarkSearchKNNParallel(b *testing.B, workers int) {
+	hashes, _ := makeRandomHammingTree(500, 4)
+	tree := new(BKTree)
+	for _, h := range hashes {
+		tree.Add(slowHash{h})
+	}
+	b.ResetTimer()
+
+	visited := 0
+	for i := 0; i < b.N; i++ {
+		_, count := tree.SearchKNNParallel(slowHash{hashes[i%len(hashes)] ^ 0xff}, 20, workers)
+		visited += count
+	}
+	b.ReportMetric(float64(visited)/float64(b.N), "distances/op")
+}
+
+func BenchmarkBKTree_SearchKNNParallel_1(b *testing.B)  { benchmarkSearchKNNParallel(b, 1) }
+func BenchmarkBKTree_SearchKNNParallel_8(b *testing.B)  { benchmarkSearchKNNParallel(b, 8) }
+func BenchmarkBKTree_SearchKNNParallel_32(b *testing.B) { benchmarkSearchKNNParallel(b, 32) }
@@ -0,0 +1,167 @@
+package go_bk_tree
+
+import (
+	"container/heap"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func bruteForceKNN(hashes []Hamming64, val Hamming64, k int) []Distance {
+	dists := make([]Distance, len(hashes))
+	for i, h := range hashes {
+		dists[i] = h.DistanceFrom(val)
+	}
+	sort.Slice(dists, func(i, j int) bool { return dists[i] < dists[j] })
+	return dists[:k]
+}
+
+func matchDistances(matches []Match) []Distance {
+	dists := make([]Distance, len(matches))
+	for i, m := range matches {
+		dists[i] = m.Distance
+	}
+	return dists
+}
+
+func TestBKTree_SearchKNN(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 6)
+	query := Hamming64(12345)
+	results, count := tree.SearchKNN(query, 10)
+	expected := bruteForceKNN(hashes, query, 10)
+	if got := matchDistances(results); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+	if count >= tree.Size {
+		t.Errorf("expected pruning, got %d distance computations for %d nodes", count, tree.Size)
+	}
+	for _, m := range results {
+		if m.Value.DistanceFrom(query) != m.Distance {
+			t.Errorf("wrong distance for %v", m.Value)
+		}
+	}
+	if results, _ := new(BKTree).SearchKNN(query, 3); len(results) != 0 {
+		t.Errorf("expected no results, got: %v", results)
+	}
+}
+
+// A best-first search returning the first value found within radius 2 of the query,
+// exploring the most promising subtrees first
+func ExampleNodeHeap() {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	query, radius := Word("sort"), Distance(2)
+
+	frontier := &NodeHeap{{Node: tree.Root, Bound: 0}}
+	for frontier.Len() > 0 {
+		cand := heap.Pop(frontier).(ScoredNode)
+		if cand.Bound > radius {
+			break
+		}
+		dist := cand.Node.DistanceFrom(query)
+		if dist <= radius {
+			fmt.Println(cand.Node.MetricTensor, dist)
+			break
+		}
+		for d, child := range cand.Node.Children {
+			bound := dist - d
+			if bound < 0 {
+				bound = -bound
+			}
+			heap.Push(frontier, ScoredNode{Node: child, Bound: max(bound, cand.Bound)})
+		}
+	}
+	// Output:
+	// soft 2
+}
+
+func TestNearestJoin(t *testing.T) {
+	hashes, b := makeRandomHammingTree(500, 3)
+	a := make([]MetricTensor, 200)
+	for i := range a {
+		a[i] = hashes[i] ^ Hamming64(1<<(i%64))
+	}
+	matches, parallel := NearestJoin(a, b), NearestJoinParallel(a, b)
+	for i, m := range matches {
+		if m.Distance != 0 && m.Distance != 1 || m.Value.DistanceFrom(a[i]) != m.Distance {
+			t.Errorf("%d: unexpected match %v", i, m)
+		}
+		if parallel[i].Distance != m.Distance {
+			t.Errorf("%d: expected: %d, got: %d", i, m.Distance, parallel[i].Distance)
+		}
+	}
+	if empty := NearestJoin(a[:1], new(BKTree)); empty[0].Value != nil {
+		t.Errorf("expected a zero match, got: %v", empty[0])
+	}
+}
+
+func TestBKTree_SearchKNNProgressive(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(2000, 6)
+	for i, q := range hashes[:20] {
+		q ^= Hamming64(0xff << (i % 56))
+		var stages [][]Match
+		results, _ := tree.SearchKNNProgressive(q, 5, 1, func(m []Match) { stages = append(stages, m) })
+		exact, _ := tree.SearchKNN(q, 5)
+		if expected, got := matchDistances(exact), matchDistances(results); !reflect.DeepEqual(expected, got) {
+			t.Errorf("query %d: expected: %v, got: %v", i, expected, got)
+		}
+		if len(stages) < 2 || !reflect.DeepEqual(stages[len(stages)-1], results) {
+			t.Errorf("query %d: expected several stages ending with the result, got: %v", i, stages)
+		}
+	}
+	// soft is visited but not pruned at radius 0, both values are still out of it
+	words := createNewTreeFromWords([]string{"some", "soft"})
+	if results, _ := words.SearchKNNProgressive(Word("sxxe"), 2, 0, nil); len(results) != 2 {
+		t.Errorf("expected: [some soft], got: %v", results)
+	}
+}
+
+func TestBKTree_AllPairsWithin(t *testing.T) {
+	words := []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon", "sole"}
+	tree := createNewTreeFromWords(words)
+	got := make(map[[2]string]bool)
+	for _, p := range tree.AllPairsWithin(2) {
+		a, b := min(p[0].ToString(), p[1].ToString()), max(p[0].ToString(), p[1].ToString())
+		if a == b || got[[2]string{a, b}] {
+			t.Errorf("unexpected pair: %v", p)
+		}
+		got[[2]string{a, b}] = true
+	}
+	expected := make(map[[2]string]bool)
+	for i, a := range words {
+		for _, b := range words[i+1:] {
+			if Word(a).DistanceFrom(Word(b)) <= 2 {
+				expected[[2]string{min(a, b), max(a, b)}] = true
+			}
+		}
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected: %v, got: %v", expected, got)
+	}
+	if pairs := new(BKTree).AllPairsWithin(2); len(pairs) != 0 {
+		t.Errorf("expected no pairs, got: %v", pairs)
+	}
+}
+
+func TestBKTree_NearestExcluding(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(3000, 31)
+	query := hashes[5] ^ 0x3
+	knn, _ := tree.SearchKNN(query, 4)
+	excluded := map[ID]bool{IDOf(knn[0].Value): true, IDOf(knn[1].Value): true}
+	val, dist, ok := tree.NearestExcluding(query, excluded)
+	if !ok || dist != knn[2].Distance || excluded[IDOf(val)] {
+		t.Errorf("expected the third nearest at %d, got: %v at %d (%v)", knn[2].Distance, val, dist, ok)
+	}
+
+	keyed := new(BKTree)
+	keyed.Add(Keyed{Word("some"), "a"})
+	keyed.Add(Keyed{Word("same"), "b"})
+	excluded = map[ID]bool{"a": true, "b": true}
+	if _, _, ok := keyed.NearestExcluding(Word("some"), excluded); ok {
+		t.Errorf("expected every value to be excluded")
+	}
+	delete(excluded, "b")
+	if val, dist, _ := keyed.NearestExcluding(Word("some"), excluded); IDOf(val) != "b" || dist != 2 {
+		t.Errorf("expected: b at 2, got: %v at %d", val, dist)
+	}
+}
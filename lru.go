@@ -0,0 +1,73 @@
+package go_bk_tree
+
+import "container/list"
+
+// lru is a size-bounded least-recently-used cache, it is not safe for concurrent use
+type lru[K comparable, V any] struct {
+	capacity int
+	order    *list.List // front is the most recently used
+	items    map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key K
+	val V
+}
+
+func newLRU[K comparable, V any](capacity int) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+func (c *lru[K, V]) get(key K) (V, bool) {
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*lruEntry[K, V]).val, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (c *lru[K, V]) put(key K, val V) {
+	if c.capacity <= 0 {
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry[K, V]).val = val
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key, val})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+func (c *lru[K, V]) remove(key K) {
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// each calls f with every entry, from the most to the least recently used
+func (c *lru[K, V]) each(f func(key K, val V)) {
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*lruEntry[K, V])
+		f(entry.key, entry.val)
+	}
+}
+
+func (c *lru[K, V]) len() int {
+	return c.order.Len()
+}
+
+func (c *lru[K, V]) clear() {
+	c.order.Init()
+	c.items = make(map[K]*list.Element)
+}
@@ -0,0 +1,50 @@
+package go_bk_tree
+
+import "sort"
+
+const (
+	// medoidCandidates is the minimum number of values near the root scored by Medoids
+	medoidCandidates = 32
+	// medoidSamples is the number of values the candidates of Medoids are scored against
+	medoidSamples = 256
+)
+
+// Medoids returns up to n approximately central values, ranked by ascending average
+// distance to a sample of the tree. It is approximate twice over: only the first
+// max(4n, 32) values in level order (see Levels) are candidates, since values near
+// the root tend to be spread over the space, and their average distance is taken
+// over at most 256 values picked evenly in level order, not the whole tree. It costs
+// at most candidates × 256 distance computations and is deterministic.
+func (tree *BKTree) Medoids(n int) []MetricTensor {
+	var all []MetricTensor
+	for _, level := range tree.Levels() {
+		all = append(all, level...)
+	}
+	if n <= 0 || len(all) == 0 {
+		return make([]MetricTensor, 0)
+	}
+	candidates := all[:min(len(all), max(4*n, medoidCandidates))]
+	samples := all
+	if len(all) > medoidSamples {
+		samples = make([]MetricTensor, medoidSamples)
+		for i := range samples {
+			samples[i] = all[i*len(all)/medoidSamples]
+		}
+	}
+	scores := make([]int, len(candidates))
+	for i, c := range candidates {
+		for _, s := range samples {
+			scores[i] += int(c.DistanceFrom(s))
+		}
+	}
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] < scores[order[j]] })
+	medoids := make([]MetricTensor, 0, min(n, len(order)))
+	for _, i := range order[:min(n, len(order))] {
+		medoids = append(medoids, candidates[i])
+	}
+	return medoids
+}
@@ -0,0 +1,25 @@
+package go_bk_tree
+
+import "testing"
+
+func TestBKTree_Medoids(t *testing.T) {
+	tree := new(BKTree)
+	for _, n := range []int{0, 100, 48, 50, 52, 10, 90, 49, 51} {
+		tree.Add(bigNumber(n))
+	}
+	medoids := tree.Medoids(3)
+	if len(medoids) != 3 || medoids[0] != bigNumber(50) {
+		t.Errorf("expected 50 first, got: %v", medoids)
+	}
+	for _, m := range medoids {
+		if d := m.DistanceFrom(bigNumber(50)); d > 2 {
+			t.Errorf("expected values close to the center, got: %v", medoids)
+		}
+	}
+	if all := tree.Medoids(100); len(all) != tree.Size {
+		t.Errorf("expected: %d, got: %d", tree.Size, len(all))
+	}
+	if empty := new(BKTree).Medoids(3); len(empty) != 0 {
+		t.Errorf("expected no medoids, got: %v", empty)
+	}
+}
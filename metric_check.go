@@ -0,0 +1,68 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// ErrTriangleInequality is wrapped by the violations reported by MetricCheck
+var ErrTriangleInequality = errors.New("go_bk_tree: metric violates the triangle inequality")
+
+// MetricCheck is a development aid catching metrics that break the triangle inequality,
+// which silently makes Search miss matches. After each Add or Search of a tree with a
+// MetricCheck, Samples random pairs (a, b) of the visited values are picked, d(a, b) is
+// computed and the three inequalities between a, b and the query are verified.
+type MetricCheck struct {
+	Samples int
+	// Rand picks the pairs, a nil Rand uses a fixed seed
+	Rand *rand.Rand
+	// OnViolation is called for every violation found, if set
+	OnViolation func(err error)
+
+	mu         sync.Mutex
+	violations []error
+}
+
+// Violations returns every violation found so far
+func (mc *MetricCheck) Violations() []error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return append([]error(nil), mc.violations...)
+}
+
+// Err returns the violations found so far joined in a single error, nil if there is none
+func (mc *MetricCheck) Err() error {
+	return errors.Join(mc.Violations()...)
+}
+
+// check samples pairs of visited values, each with its distance from val
+func (mc *MetricCheck) check(val MetricTensor, visited []Match) {
+	if len(visited) < 2 {
+		return
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.Rand == nil {
+		mc.Rand = rand.New(rand.NewSource(1))
+	}
+	for n := 0; n < mc.Samples; n++ {
+		i, j := mc.Rand.Intn(len(visited)), mc.Rand.Intn(len(visited))
+		if i == j {
+			continue
+		}
+		a, b := visited[i], visited[j]
+		ab := a.Value.DistanceFrom(b.Value)
+		if a.Distance > ab+b.Distance || b.Distance > ab+a.Distance || ab > a.Distance+b.Distance {
+			err := fmt.Errorf("%w: d(%s, %s) = %d, d(%s, %s) = %d, d(%s, %s) = %d", ErrTriangleInequality,
+				a.Value.ToString(), b.Value.ToString(), ab,
+				a.Value.ToString(), val.ToString(), a.Distance,
+				b.Value.ToString(), val.ToString(), b.Distance)
+			mc.violations = append(mc.violations, err)
+			if mc.OnViolation != nil {
+				mc.OnViolation(err)
+			}
+		}
+	}
+}
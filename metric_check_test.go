@@ -0,0 +1,95 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// squaredNumber uses the squared difference, which is not a metric
+type squaredNumber int
+
+func (n squaredNumber) DistanceFrom(other MetricTensor) Distance {
+	d := int(n) - int(other.(squaredNumber))
+	return Distance(d * d)
+}
+
+func (n squaredNumber) ToString() string {
+	return strconv.Itoa(int(n))
+}
+
+func TestMetricCheck(t *testing.T) {
+	good := &BKTree{MetricCheck: &MetricCheck{Samples: 5}}
+	hashes, _ := makeRandomHammingTree(500, 9)
+	for _, h := range hashes {
+		good.Add(h)
+	}
+	good.Search(hashes[0], 10)
+	if err := good.MetricCheck.Err(); err != nil {
+		t.Errorf("expected no violation, got: %v", err)
+	}
+
+	reported := 0
+	bad := &BKTree{MetricCheck: &MetricCheck{Samples: 5, OnViolation: func(error) { reported++ }}}
+	for i := 0; i < 100; i++ {
+		bad.Add(squaredNumber(i))
+	}
+	bad.Search(squaredNumber(50), 100)
+	err := bad.MetricCheck.Err()
+	if !errors.Is(err, ErrTriangleInequality) {
+		t.Errorf("expected: %v, got: %v", ErrTriangleInequality, err)
+	}
+	if reported != len(bad.MetricCheck.Violations()) {
+		t.Errorf("expected: %d, got: %d", len(bad.MetricCheck.Violations()), reported)
+	}
+}
+
+func TestBKTree_SearchExhaustive_NonMetric(t *testing.T) {
+	tree := new(BKTree)
+	for i := 0; i < 200; i++ {
+		tree.Add(squaredNumber((i * 37) % 200))
+	}
+	query, radius := squaredNumber(101), Distance(30)
+	expected := 0
+	for i := 0; i < 200; i++ {
+		if query.DistanceFrom(squaredNumber(i)) <= radius {
+			expected++
+		}
+	}
+	results, count := tree.SearchExhaustive(query, radius)
+	if len(results) != expected || count != tree.Size {
+		t.Errorf("expected: %d results over %d nodes, got: %d over %d", expected, tree.Size, len(results), count)
+	}
+}
+
+// flakyWord is a Word whose distances grow on every computation
+type flakyWord struct {
+	Word
+	calls *int
+}
+
+func (w flakyWord) DistanceFrom(other MetricTensor) Distance {
+	*w.calls += 1
+	return w.Word.DistanceFrom(other.(flakyWord).Word) + Distance(*w.calls)
+}
+
+func TestBKTree_CheckDeterminism(t *testing.T) {
+	calls := 0
+	tree := &BKTree{CheckDeterminism: true}
+	if err := tree.Add(flakyWord{Word("some"), &calls}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Add(flakyWord{Word("soft"), &calls}); !errors.Is(err, ErrNondeterministicMetric) {
+		t.Errorf("expected: %v, got: %v", ErrNondeterministicMetric, err)
+	}
+	if tree.Size != 1 {
+		t.Errorf("expected the value not to be added, got size: %d", tree.Size)
+	}
+	if _, _, err := tree.SearchChecked(flakyWord{Word("same"), &calls}, 2); !errors.Is(err, ErrNondeterministicMetric) {
+		t.Errorf("expected: %v, got: %v", ErrNondeterministicMetric, err)
+	}
+	tree.CheckDeterminism = false
+	if err := tree.Add(flakyWord{Word("soft"), &calls}); err != nil {
+		t.Errorf("expected no check by default, got: %v", err)
+	}
+}
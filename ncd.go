@@ -0,0 +1,72 @@
+package go_bk_tree
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"sync"
+)
+
+// NCDScale is the factor the normalized compression distance of two Blobs is
+// multiplied by before being rounded to a Distance
+const NCDScale = 1000
+
+// BlobCompressor returns the compressed size of data, which Blob distances are
+// computed from. It defaults to GzipSize; set it before building a tree (e.g. to a
+// zstd or brotli size) and keep it for the tree's lifetime, distances computed with
+// different compressors are not comparable.
+var BlobCompressor func(data []byte) int = GzipSize
+
+// Blob is a built-in MetricTensor for arbitrary bytes, the distance between two blobs
+// is their normalized compression distance
+//
+//	NCD(x, y) = (C(xy) - min(C(x), C(y))) / max(C(x), C(y))
+//
+// scaled by NCDScale, where C is BlobCompressor. Similar blobs compress well together,
+// so it finds near duplicates without knowing anything about their format.
+//
+// NCD is only approximately a metric: real compressors are not idempotent, C(xy) and
+// C(yx) differ slightly and headers add a constant, so small distances are noisy and
+// the triangle inequality can be violated by a few percent of NCDScale. Search with a
+// somewhat larger radius than needed, or use SearchExhaustive when every match
+// matters; MetricCheck reports the violations met while building. Each distance
+// compresses both blobs and their concatenation, so keep blobs small or the tree
+// modest in size.
+type Blob []byte
+
+func (b Blob) DistanceFrom(other MetricTensor) Distance {
+	o := other.(Blob)
+	cx, cy := BlobCompressor(b), BlobCompressor(o)
+	xy := make([]byte, 0, len(b)+len(o))
+	xy = append(append(xy, b...), o...)
+	cxy := BlobCompressor(xy)
+	lo, hi := min(cx, cy), max(cx, cy)
+	if hi == 0 {
+		return 0
+	}
+	ncd := max(cxy-lo, 0)
+	return Distance((NCDScale*ncd + hi/2) / hi)
+}
+
+// ToString returns the blob in standard base64, decode it with base64.StdEncoding in
+// the factory of FromJson
+func (b Blob) ToString() string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+var gzipWriters = sync.Pool{New: func() any {
+	w, _ := gzip.NewWriterLevel(nil, gzip.BestCompression)
+	return w
+}}
+
+// GzipSize returns the size of data compressed with gzip at gzip.BestCompression,
+// the default BlobCompressor
+func GzipSize(data []byte) int {
+	var buf bytes.Buffer
+	w := gzipWriters.Get().(*gzip.Writer)
+	w.Reset(&buf)
+	w.Write(data)
+	w.Close()
+	gzipWriters.Put(w)
+	return buf.Len()
+}
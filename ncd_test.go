@@ -0,0 +1,49 @@
+package go_bk_tree
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestBlob_DistanceFrom(t *testing.T) {
+	text := Blob(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 20))
+	edited := Blob(strings.Replace(string(text), "lazy", "sleepy", 1))
+	other := Blob(strings.Repeat("0123456789abcdef", 56))
+	if d := text.DistanceFrom(text); d > NCDScale/10 {
+		t.Errorf("expected a blob to be close to itself, got: %d", d)
+	}
+	near, far := text.DistanceFrom(edited), text.DistanceFrom(other)
+	if near >= far {
+		t.Errorf("expected the edited text to be closer than unrelated data: %d >= %d", near, far)
+	}
+	if d := Blob(nil).DistanceFrom(Blob(nil)); d > NCDScale/10 {
+		t.Errorf("expected empty blobs to be close, got: %d", d)
+	}
+}
+
+func TestBlob_Compressor(t *testing.T) {
+	defer func(c func([]byte) int) { BlobCompressor = c }(BlobCompressor)
+	BlobCompressor = func(data []byte) int { return len(bytes.Fields(data)) }
+	if d := Blob("a b ").DistanceFrom(Blob("c d")); d != NCDScale {
+		t.Errorf("expected: %d, got: %d", NCDScale, d)
+	}
+}
+
+func TestBKTree_Search_Blob(t *testing.T) {
+	base := strings.Repeat("lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 10)
+	tree := new(BKTree)
+	tree.Add(Blob(base))
+	tree.Add(Blob(strings.Replace(base, "dolor", "color", 1)))
+	tree.Add(Blob(strings.Repeat("a completely unrelated record #", 18)))
+	tree.Add(Blob(bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef, 0x01}, 120)))
+	results, _ := tree.SearchExhaustive(Blob(base), NCDScale/5)
+	if len(results) != 2 {
+		t.Errorf("expected the text and its near duplicate, got %d results", len(results))
+	}
+	decoded, err := base64.StdEncoding.DecodeString(Blob(base).ToString())
+	if err != nil || string(decoded) != base {
+		t.Errorf("expected ToString to round trip, got: %q, %v", decoded, err)
+	}
+}
@@ -0,0 +1,32 @@
+package go_bk_tree
+
+// Normalized is the value stored by a tree with a Normalizer: distances are computed
+// between the canonical Keys, while Original is kept for display (ToString) and is
+// what the caller gets back through Value.Original from Search and friends.
+type Normalized struct {
+	Original MetricTensor
+	Key      MetricTensor
+}
+
+func (n Normalized) DistanceFrom(other MetricTensor) Distance {
+	if o, ok := other.(Normalized); ok {
+		other = o.Key
+	}
+	return n.Key.DistanceFrom(other)
+}
+
+func (n Normalized) ToString() string {
+	return n.Original.ToString()
+}
+
+// normalize wraps val into a Normalized for a tree with a Normalizer, a value
+// already wrapped (e.g. taken from a search result) is returned as is
+func (tree *BKTree) normalize(val MetricTensor) MetricTensor {
+	if tree.Normalizer == nil {
+		return val
+	}
+	if _, ok := val.(Normalized); ok {
+		return val
+	}
+	return Normalized{Original: val, Key: tree.Normalizer(val)}
+}
@@ -0,0 +1,49 @@
+package go_bk_tree
+
+import (
+	"strings"
+	"testing"
+)
+
+func lowerWord(val MetricTensor) MetricTensor {
+	return Word(strings.ToLower(string(val.(Word))))
+}
+
+func TestBKTree_Normalizer(t *testing.T) {
+	tree := &BKTree{Normalizer: lowerWord}
+	for _, w := range []string{"Cafe", "cafe", "CAFE", "cafes", "tea"} {
+		tree.Add(Word(w))
+	}
+	if tree.Size != 3 {
+		t.Errorf("expected size: 3, got: %d", tree.Size)
+	}
+	results, _ := tree.Search(Word("CaFe"), 0)
+	if len(results) != 1 || results[0].(Normalized).Original != Word("Cafe") || results[0].ToString() != "Cafe" {
+		t.Errorf("expected the original Cafe, got: %v", results)
+	}
+	node := tree.Find(Word("TEA"))
+	if node == nil {
+		t.Fatal("expected TEA to be found as tea")
+	}
+	if !tree.RemoveNode(node) || tree.Find(Word("tea")) != nil || tree.Size != 2 {
+		t.Errorf("expected tea to be removed, size: %d", tree.Size)
+	}
+	if matches, _ := tree.SearchKNN(Word("CAFES"), 1); matches[0].Value.ToString() != "cafes" || matches[0].Distance != 0 {
+		t.Errorf("expected cafes at distance 0, got: %v", matches)
+	}
+}
+
+func TestBKTree_ResultKey(t *testing.T) {
+	tree := &BKTree{ResultKey: func(val MetricTensor) string { return strings.ToLower(val.ToString()) }}
+	for _, w := range []string{"Some", "some", "SOME", "soft"} {
+		tree.Add(Word(w))
+	}
+	results, _ := tree.Search(Word("some"), 8)
+	if got := sortedStrings(results); len(got) != 2 || got[0] != "soft" || got[1] != "some" {
+		t.Errorf("expected: [soft some], got: %v", got)
+	}
+	facets, _ := tree.SearchFaceted(Word("SOMe"), 8)
+	if len(facets) != 2 || facets[0] != (Facet{Word("SOME"), 3, 2}) || facets[1].Count != 1 {
+		t.Errorf("expected SOME to represent 3 values, got: %v", facets)
+	}
+}
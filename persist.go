@@ -0,0 +1,85 @@
+package go_bk_tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ChangeOp is the kind of mutation recorded by AppendChange
+type ChangeOp string
+
+const (
+	ChangeAdd    ChangeOp = "add"
+	ChangeRemove ChangeOp = "remove"
+)
+
+type changeRecord struct {
+	Op    ChangeOp `json:"op"`
+	Value string   `json:"value"`
+}
+
+// WriteSnapshot writes the full tree to w in the ToJson form.
+//
+// Together with AppendChange and LoadWithChanges it provides incremental persistence:
+// write a snapshot once, append a change record for every later Add or Remove, and
+// replay them on load. To compact, load the snapshot with its changes, write a new
+// snapshot of the result and start a new, empty change log.
+func (tree *BKTree) WriteSnapshot(w io.Writer) error {
+	data, err := tree.ToJson()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// AppendChange writes a single change record (one JSON object per line) to w, the
+// caller is responsible for applying the same change to its tree
+func AppendChange(w io.Writer, op ChangeOp, val MetricTensor) error {
+	if op != ChangeAdd && op != ChangeRemove {
+		return fmt.Errorf("go_bk_tree: unknown change op %q", op)
+	}
+	data, err := json.Marshal(changeRecord{op, val.ToString()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// LoadWithChanges reads a snapshot written by WriteSnapshot and replays the change
+// records of changes on top of it, in order. A nil changes only loads the snapshot.
+func LoadWithChanges(snapshot, changes io.Reader, factory func(string) MetricTensor) (*BKTree, error) {
+	data, err := io.ReadAll(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := FromJson(data, factory)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		return tree, nil
+	}
+	dec := json.NewDecoder(changes)
+	for n := 0; ; n++ {
+		var record changeRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			return tree, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("go_bk_tree: change record %d: %w", n, err)
+		}
+		val := factory(record.Value)
+		switch record.Op {
+		case ChangeAdd:
+			if err := tree.Add(val); err != nil {
+				return nil, fmt.Errorf("go_bk_tree: change record %d: %w", n, err)
+			}
+		case ChangeRemove:
+			tree.RemoveNode(tree.Find(val))
+		default:
+			return nil, fmt.Errorf("go_bk_tree: change record %d: unknown op %q", n, record.Op)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package go_bk_tree
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadWithChanges(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same"})
+	var snapshot, changes bytes.Buffer
+	if err := tree.WriteSnapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{"mole", "soda"} {
+		tree.Add(Word(w))
+		AppendChange(&changes, ChangeAdd, Word(w))
+	}
+	tree.RemoveNode(tree.Find(Word("soft")))
+	AppendChange(&changes, ChangeRemove, Word("soft"))
+	if err := AppendChange(&changes, "rename", Word("soft")); err == nil {
+		t.Error("expected an error for an unknown op")
+	}
+
+	loaded, err := LoadWithChanges(bytes.NewReader(snapshot.Bytes()), bytes.NewReader(changes.Bytes()), wordFactory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, got []MetricTensor
+	for v := range tree.All() {
+		expected = append(expected, v)
+	}
+	for v := range loaded.All() {
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) || loaded.Size != tree.Size {
+		t.Errorf("expected: %v, got: %v", sortedStrings(expected), sortedStrings(got))
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	torn := changes.String()[:changes.Len()-5]
+	if _, err := LoadWithChanges(bytes.NewReader(snapshot.Bytes()), strings.NewReader(torn), wordFactory); err == nil {
+		t.Error("expected an error for a torn change record")
+	}
+}
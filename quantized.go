@@ -0,0 +1,36 @@
+package go_bk_tree
+
+// FloatMetric is a value whose distances are floats (e.g. Euclidean vectors), it is
+// turned into a MetricTensor by Quantized
+type FloatMetric interface {
+	FloatDistanceFrom(other FloatMetric) float64
+	ToString() string
+}
+
+// Quantized decorates a FloatMetric into a MetricTensor, the float distance is
+// multiplied by Scale and rounded with DistanceFromFloat. All the values of a tree,
+// and its queries, must use the same Scale.
+//
+// The scale trades precision for pruning: rounding moves every distance by up to
+// 0.5/Scale, so distances closer than that become equal (and values closer than
+// that are dropped by Add as duplicates), while a larger Scale spreads the children
+// of a node over more buckets, which makes the tree wider and each radius step
+// smaller. Rounding can also break the triangle inequality by 1: search with a
+// radius 1 larger than needed when every match matters.
+//
+// Example:
+//
+//	tree.Add(Quantized{Value: Vector{0.5, 1.2}, Scale: 1000})
+//	tree.Search(Quantized{Value: Vector{0.4, 1.2}, Scale: 1000}, 150)
+type Quantized struct {
+	Value FloatMetric
+	Scale float64
+}
+
+func (q Quantized) DistanceFrom(other MetricTensor) Distance {
+	return DistanceFromFloat(q.Value.FloatDistanceFrom(other.(Quantized).Value) * q.Scale)
+}
+
+func (q Quantized) ToString() string {
+	return q.Value.ToString()
+}
@@ -0,0 +1,37 @@
+package go_bk_tree
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// point is a 2D point with the Euclidean distance
+type point struct{ x, y float64 }
+
+func (p point) FloatDistanceFrom(other FloatMetric) float64 {
+	o := other.(point)
+	return math.Hypot(p.x-o.x, p.y-o.y)
+}
+
+func (p point) ToString() string {
+	return fmt.Sprintf("%g,%g", p.x, p.y)
+}
+
+func TestQuantized(t *testing.T) {
+	q := func(x, y float64) Quantized { return Quantized{Value: point{x, y}, Scale: 100} }
+	if d := q(0, 0).DistanceFrom(q(3, 4)); d != 500 {
+		t.Errorf("expected: %d, got: %d", 500, d)
+	}
+	tree := new(BKTree)
+	for _, p := range []Quantized{q(0, 0), q(1, 0), q(0, 1.5), q(3, 4), q(0, 0.001)} {
+		tree.Add(p)
+	}
+	if tree.Size != 4 {
+		t.Errorf("expected points closer than 0.005 to be duplicates, got size: %d", tree.Size)
+	}
+	results, _ := tree.Search(q(0.1, 0.1), 100)
+	if got := sortedStrings(results); len(got) != 2 || got[0] != "0,0" || got[1] != "1,0" {
+		t.Errorf("expected: [0,0 1,0], got: %v", got)
+	}
+}
@@ -0,0 +1,196 @@
+package go_bk_tree
+
+import "sync"
+
+type queryKey struct {
+	query  string
+	radius Distance
+}
+
+type cachedResult struct {
+	results []MetricTensor
+	count   int
+	visited map[*BkTreeNode]Distance // with TrackNodes, the distance of every visited node
+}
+
+// QueryCache wraps a tree with an LRU cache of Search results keyed by the query's
+// ToString and the radius, which saves the traversal of repeated queries on a
+// read-mostly index. The cache is cleared by every mutation made through the
+// QueryCache; if the underlying tree is mutated directly, Invalidate must be called
+// or stale results will be returned. It is safe for concurrent use.
+//
+// With TrackNodes set, every cached query also records the nodes it visited with their
+// distance, and Add and RemoveNode only drop the queries the change can affect: a new
+// value hangs below a single node, so only the queries that visited that node with its
+// bucket in their search window could reach it, and removing a leaf only affects the
+// queries that visited it. This takes one map entry per visited node per cached query,
+// plus a descent from the root and a scan of every cached query per Add. Removing a
+// node with children, an Add evicting values (MaxSize) and a tree with MaxResults
+// (whose results depend on the traversal order) fall back to clearing the cache.
+type QueryCache struct {
+	// TrackNodes enables the selective invalidation, it must be set before the first Search
+	TrackNodes bool
+
+	mu   sync.RWMutex // guards the tree
+	tree *BKTree
+
+	cacheMu sync.Mutex // guards the cache and the stats
+	cache   *lru[queryKey, cachedResult]
+	hits    uint64
+	misses  uint64
+}
+
+// NewQueryCache wraps tree with a cache holding the results of at most maxSize queries
+func NewQueryCache(tree *BKTree, maxSize int) *QueryCache {
+	return &QueryCache{tree: tree, cache: newLRU[queryKey, cachedResult](maxSize)}
+}
+
+// Search returns the cached results of the query if any, otherwise it runs Search on
+// the tree and caches its results. The returned slice is a copy owned by the caller.
+func (qc *QueryCache) Search(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	if val == nil {
+		return make([]MetricTensor, 0), 0
+	}
+	qc.mu.RLock()
+	defer qc.mu.RUnlock()
+	key := queryKey{val.ToString(), radius}
+	qc.cacheMu.Lock()
+	cached, ok := qc.cache.get(key)
+	if ok {
+		qc.hits += 1
+	} else {
+		qc.misses += 1
+	}
+	qc.cacheMu.Unlock()
+	if !ok {
+		cached = qc.search(val, radius)
+		qc.cacheMu.Lock()
+		qc.cache.put(key, cached)
+		qc.cacheMu.Unlock()
+	}
+	return append([]MetricTensor(nil), cached.results...), cached.count
+}
+
+// search runs a query on the tree, recording its visited nodes with TrackNodes
+func (qc *QueryCache) search(val MetricTensor, radius Distance) cachedResult {
+	if !qc.TrackNodes {
+		if qc.tree.Root == nil {
+			return cachedResult{results: make([]MetricTensor, 0)}
+		}
+		results, count := qc.tree.Search(val, radius)
+		return cachedResult{results: results, count: count}
+	}
+	visited := make(map[*BkTreeNode]Distance)
+	results := make([]MetricTensor, 0, 5)
+	count := qc.tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		visited[node] = dist
+		if dist <= radius {
+			results = append(results, node.MetricTensor)
+		}
+		return !qc.tree.capped(len(results))
+	})
+	return cachedResult{qc.tree.collapse(val, results), count, visited}
+}
+
+// Add a value to the tree and clear the cache, or only the affected queries with TrackNodes
+func (qc *QueryCache) Add(val MetricTensor) error {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	if !qc.TrackNodes {
+		defer qc.Invalidate()
+		return qc.tree.Add(val)
+	}
+	version, size := qc.tree.Version(), qc.tree.Size
+	err := qc.tree.Add(val)
+	switch {
+	case qc.tree.Version() == version:
+		// rejected, the results are the same
+	case qc.tree.Version() != version+1 || qc.tree.MaxResults > 0 || size == 0:
+		// an evicting Add bumps the version more than once, whatever the Size
+		qc.Invalidate()
+	case qc.tree.Size == size:
+		// counted as a duplicate, the results are the same
+	default:
+		parent, bucket := qc.tree.parentOf(val)
+		qc.invalidateWhere(func(key queryKey, cached cachedResult) bool {
+			dist, ok := cached.visited[parent]
+			if !ok {
+				return false
+			}
+			low, high := searchWindow(dist, key.radius)
+			return bucket >= low && bucket <= high
+		})
+	}
+	return err
+}
+
+// RemoveNode removes a node from the tree and clears the cache, or only the affected
+// queries with TrackNodes
+func (qc *QueryCache) RemoveNode(node *BkTreeNode) bool {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	leaf := node != nil && len(node.Children) == 0
+	version := qc.tree.Version()
+	removed := qc.tree.RemoveNode(node)
+	if removed && qc.TrackNodes && leaf && qc.tree.Version() == version+1 && qc.tree.MaxResults == 0 {
+		qc.invalidateWhere(func(key queryKey, cached cachedResult) bool {
+			_, ok := cached.visited[node]
+			return ok
+		})
+	} else if removed || !qc.TrackNodes {
+		qc.Invalidate()
+	}
+	return removed
+}
+
+// invalidateWhere drops the cached queries for which stale returns true
+func (qc *QueryCache) invalidateWhere(stale func(key queryKey, cached cachedResult) bool) {
+	qc.cacheMu.Lock()
+	defer qc.cacheMu.Unlock()
+	var keys []queryKey
+	qc.cache.each(func(key queryKey, cached cachedResult) {
+		if stale(key, cached) {
+			keys = append(keys, key)
+		}
+	})
+	for _, key := range keys {
+		qc.cache.remove(key)
+	}
+}
+
+// parentOf returns the parent of the node holding val and its bucket, the tree must
+// hold val below its root
+func (tree *BKTree) parentOf(val MetricTensor) (*BkTreeNode, Distance) {
+	val = tree.normalize(val)
+	var parent *BkTreeNode
+	var bucket Distance
+	node := tree.Root
+	for {
+		dist := node.DistanceFrom(val)
+		if dist <= tree.Epsilon {
+			return parent, bucket
+		}
+		parent, bucket, node = node, dist, node.Children[dist]
+	}
+}
+
+// Invalidate clears the cache, stats are kept
+func (qc *QueryCache) Invalidate() {
+	qc.cacheMu.Lock()
+	defer qc.cacheMu.Unlock()
+	qc.cache.clear()
+}
+
+// Len returns the number of cached queries
+func (qc *QueryCache) Len() int {
+	qc.cacheMu.Lock()
+	defer qc.cacheMu.Unlock()
+	return qc.cache.len()
+}
+
+// Stats returns the number of cache hits and misses so far
+func (qc *QueryCache) Stats() (hits, misses uint64) {
+	qc.cacheMu.Lock()
+	defer qc.cacheMu.Unlock()
+	return qc.hits, qc.misses
+}
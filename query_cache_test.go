@@ -0,0 +1,100 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryCache(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"})
+	qc := NewQueryCache(tree, 2)
+	first, _ := qc.Search(Word("sort"), 2)
+	second, _ := qc.Search(Word("sort"), 2)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected: %v, got: %v", first, second)
+	}
+	if hits, misses := qc.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got: %d and %d", hits, misses)
+	}
+	qc.Search(Word("sort"), 3)
+	qc.Search(Word("mole"), 0)
+	if qc.Len() != 2 {
+		t.Errorf("expected: %d, got: %d", 2, qc.Len())
+	}
+
+	qc.Add(Word("sort"))
+	if qc.Len() != 0 {
+		t.Errorf("expected the cache to be cleared, got: %d", qc.Len())
+	}
+	results, _ := qc.Search(Word("sort"), 2)
+	if expected := []string{"soft", "sort", "sorted"}; !reflect.DeepEqual(sortedStrings(results), expected) {
+		t.Errorf("expected: %v, got: %v", expected, sortedStrings(results))
+	}
+}
+
+func TestQueryCache_TrackNodes(t *testing.T) {
+	d := Dataset{Alphabet: "abcd", MinLen: 3, MaxLen: 6, Seed: 11}
+	tree := d.Tree(500, func(s string) MetricTensor { return EditString(s) })
+	qc := NewQueryCache(tree, 100)
+	qc.TrackNodes = true
+	queries := Dataset{Alphabet: "abcd", MinLen: 3, MaxLen: 6, Seed: 12}.Strings(50)
+	check := func(what string) {
+		for _, q := range queries {
+			expected, _ := tree.Search(EditString(q), 1)
+			got, _ := qc.Search(EditString(q), 1)
+			if !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) {
+				t.Fatalf("%s, %s: expected: %v, got: %v", what, q, sortedStrings(expected), sortedStrings(got))
+			}
+		}
+	}
+	check("initial")
+	kept := 0
+	for _, w := range []string{"abcdab", "dcb", "aaaaaa", "abcd"} {
+		qc.Add(EditString(w))
+		kept += qc.Len()
+		check("add " + w)
+	}
+	if kept == 0 {
+		t.Error("expected some queries to stay cached across the additions")
+	}
+	var leaf *BkTreeNode
+	for _, node := range collectNodes(tree.Root, nil) {
+		if len(node.Children) == 0 {
+			leaf = node
+			break
+		}
+	}
+	if !qc.RemoveNode(leaf) {
+		t.Fatal("expected the leaf to be removed")
+	}
+	if qc.Len() == 0 {
+		t.Error("expected the queries that did not visit the leaf to stay cached")
+	}
+	check("remove leaf")
+	qc.RemoveNode(tree.Root)
+	if qc.Len() != 0 {
+		t.Errorf("expected the cache to be cleared, got: %d", qc.Len())
+	}
+	check("remove root")
+}
+
+func TestQueryCache_TrackNodes_EvictOldest(t *testing.T) {
+	tree := &BKTree{MaxSize: 2, Overflow: EvictOldest, Timestamps: true}
+	qc := NewQueryCache(tree, 10)
+	qc.TrackNodes = true
+	qc.Add(Word("aaaa"))
+	qc.Add(Word("bbbb"))
+	tree.Find(Word("bbbb")).AddedAt = tree.Root.AddedAt.AddDate(-1, 0, 0)
+	if got, _ := qc.Search(Word("bbbb"), 0); len(got) != 1 {
+		t.Fatalf("expected: [bbbb], got: %v", got)
+	}
+	if err := qc.Add(Word("cccc")); err != nil || tree.Size != 2 || tree.Find(Word("bbbb")) != nil {
+		t.Fatalf("expected bbbb to be evicted, got: %v", err)
+	}
+	if got, _ := qc.Search(Word("bbbb"), 0); len(got) != 0 {
+		t.Errorf("expected no results after the eviction, got: %v", got)
+	}
+	if got, _ := qc.Search(nil, 0); len(got) != 0 {
+		t.Errorf("expected no results for a nil query, got: %v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package go_bk_tree
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Keyed is a value indexed together with the key of its full record in an external
+// store, see RecordIndex. Its distances are those of the embedded value, and a Keyed
+// query is unwrapped as well.
+type Keyed struct {
+	MetricTensor
+	Key string
+}
+
+func (k Keyed) DistanceFrom(other MetricTensor) Distance {
+	if o, ok := other.(Keyed); ok {
+		other = o.MetricTensor
+	}
+	return k.MetricTensor.DistanceFrom(other)
+}
+
+// RecordIndex uses a tree of Keyed values as a lean fuzzy index over records kept
+// elsewhere (e.g. in a database): Search finds the matching values in the tree and
+// fetches only their records through Load, keeping the most recently loaded ones in
+// an LRU cache. It is safe for concurrent use as long as the tree is not modified
+// meanwhile, the cache being guarded by its own lock.
+type RecordIndex[R any] struct {
+	Tree *BKTree
+	// Load fetches the records of keys, which are distinct. A key missing from the
+	// returned map is taken as a deleted record and its match is dropped. An error fails
+	// the whole Search and nothing is cached, so that the next Search tries again. Load
+	// is called without holding any lock, possibly concurrently.
+	Load func(keys []string) (map[string]R, error)
+
+	mu    sync.Mutex // guards cache
+	cache *lru[string, R]
+}
+
+// NewRecordIndex returns a RecordIndex over tree caching at most cacheSize records,
+// 0 disables the cache
+func NewRecordIndex[R any](tree *BKTree, load func(keys []string) (map[string]R, error), cacheSize int) *RecordIndex[R] {
+	return &RecordIndex[R]{Tree: tree, Load: load, cache: newLRU[string, R](cacheSize)}
+}
+
+// Search returns the records of the values within radius of val, in the order of
+// Tree.Search, calling Load once for the keys that are not cached. It fails when a
+// match is not Keyed.
+func (ri *RecordIndex[R]) Search(val MetricTensor, radius Distance) ([]R, error) {
+	results, _ := ri.Tree.Search(val, radius)
+	keys := make([]string, len(results))
+	records := make(map[string]R, len(results))
+	var missing []string
+	requested := make(map[string]bool)
+	ri.mu.Lock()
+	for i, r := range results {
+		if n, ok := r.(Normalized); ok {
+			r = n.Original
+		}
+		k, ok := r.(Keyed)
+		if !ok {
+			ri.mu.Unlock()
+			return nil, fmt.Errorf("go_bk_tree: %s has no record key", r.ToString())
+		}
+		keys[i] = k.Key
+		if requested[k.Key] {
+			continue
+		}
+		requested[k.Key] = true
+		if rec, ok := ri.cache.get(k.Key); ok {
+			records[k.Key] = rec
+		} else {
+			missing = append(missing, k.Key)
+		}
+	}
+	ri.mu.Unlock()
+	if len(missing) > 0 {
+		loaded, err := ri.Load(missing)
+		if err != nil {
+			return nil, err
+		}
+		ri.mu.Lock()
+		for _, key := range missing {
+			if rec, ok := loaded[key]; ok {
+				records[key] = rec
+				ri.cache.put(key, rec)
+			}
+		}
+		ri.mu.Unlock()
+	}
+	found := make([]R, 0, len(keys))
+	for _, key := range keys {
+		if rec, ok := records[key]; ok {
+			found = append(found, rec)
+		}
+	}
+	return found, nil
+}
+
+// Purge empties the cache, e.g. after the records were updated in the store
+func (ri *RecordIndex[R]) Purge() {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	ri.cache.clear()
+}
@@ -0,0 +1,58 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRecordIndex(t *testing.T) {
+	store := map[string]string{"1": "some record", "2": "soft record", "3": "sorted record", "4": "mole record"}
+	var loads [][]string
+	var failure error
+	load := func(keys []string) (map[string]string, error) {
+		loads = append(loads, append([]string(nil), keys...))
+		if failure != nil {
+			return nil, failure
+		}
+		records := make(map[string]string)
+		for _, k := range keys {
+			if rec, ok := store[k]; ok {
+				records[k] = rec
+			}
+		}
+		return records, nil
+	}
+	tree := new(BKTree)
+	for k, w := range map[string]string{"1": "some", "2": "soft", "3": "sorted", "4": "mole"} {
+		tree.Add(Keyed{Word(w), k})
+	}
+	ri := NewRecordIndex(tree, load, 10)
+	records, err := ri.Search(Word("sort"), 2)
+	sort.Strings(records)
+	if err != nil || !reflect.DeepEqual(records, []string{"soft record", "sorted record"}) {
+		t.Errorf("unexpected records: %v (%v)", records, err)
+	}
+	if len(loads) != 1 || len(loads[0]) != 2 {
+		t.Errorf("expected a single load of 2 keys, got: %v", loads)
+	}
+	delete(store, "1")
+	records, err = ri.Search(Keyed{Word("sort"), ""}, 4)
+	sort.Strings(records)
+	if err != nil || !reflect.DeepEqual(records, []string{"soft record", "sorted record"}) {
+		t.Errorf("expected the deleted record to be dropped, got: %v (%v)", records, err)
+	}
+	if len(loads) != 2 || !reflect.DeepEqual(loads[1], []string{"1"}) {
+		t.Errorf("expected only the uncached key to be loaded, got: %v", loads)
+	}
+	failure = errors.New("store down")
+	ri.Purge()
+	if _, err := ri.Search(Word("sort"), 2); !errors.Is(err, failure) {
+		t.Errorf("expected: %v, got: %v", failure, err)
+	}
+	tree.Add(Word("sore"))
+	if _, err := ri.Search(Word("sore"), 0); err == nil {
+		t.Error("expected an error for a value without key")
+	}
+}
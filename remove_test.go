@@ -0,0 +1,164 @@
+package go_bk_tree
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestBKTree_RemoveNode(t *testing.T) {
+	wordsList := []string{"some", "soft", "sorted", "same", "mole", "soda", "salmon"}
+	tree := createNewTreeFromWords(wordsList)
+	node := tree.Find(Word("soft"))
+	if node == nil {
+		t.Fatal("expected to find soft")
+	}
+	if other := createNewTreeFromWords(wordsList); other.RemoveNode(node) {
+		t.Error("expected a node of another tree to be rejected")
+	}
+	if !tree.RemoveNode(node) {
+		t.Fatal("expected soft to be removed")
+	}
+	if tree.Find(Word("soft")) != nil {
+		t.Error("expected soft to be gone")
+	}
+	if tree.Size != len(wordsList)-1 {
+		t.Errorf("expected: %d, got: %d", len(wordsList)-1, tree.Size)
+	}
+	var got []MetricTensor
+	for v := range tree.All() {
+		got = append(got, v)
+	}
+	expected := []string{"mole", "salmon", "same", "soda", "some", "sorted"}
+	if !reflect.DeepEqual(sortedStrings(got), expected) {
+		t.Errorf("expected: %v, got: %v", expected, sortedStrings(got))
+	}
+	if !tree.RemoveNode(tree.Root) || tree.Size != len(wordsList)-2 {
+		t.Errorf("expected root to be removed, size: %d", tree.Size)
+	}
+}
+
+func TestBKTree_RemoveBulk(t *testing.T) {
+	// removing 10%, 50% (unlinking subtrees), 90% and 100% (rebuilding) of the values
+	for _, n := range []uint64{10, 2, 0, 1} {
+		hashes, tree := makeRandomHammingTree(1000, 5)
+		pred := func(val MetricTensor) bool { return uint64(val.(Hamming64))%n == 0 }
+		if n == 0 {
+			pred = func(val MetricTensor) bool { return uint64(val.(Hamming64))%10 != 0 }
+		}
+		expected := make([]MetricTensor, 0, len(hashes))
+		for _, h := range hashes {
+			if !pred(h) {
+				expected = append(expected, h)
+			}
+		}
+		if removed := tree.RemoveBulk(pred); removed != len(hashes)-len(expected) {
+			t.Errorf("expected: %d, got: %d", len(hashes)-len(expected), removed)
+		}
+		if tree.Size != len(expected) || tree.CountDistinct() != len(expected) {
+			t.Errorf("expected size: %d, got: %d (%d nodes)", len(expected), tree.Size, tree.CountDistinct())
+		}
+		if err := tree.Validate(); err != nil {
+			t.Error(err)
+		}
+		if got := sortedStrings(slices.Collect(tree.All())); !reflect.DeepEqual(got, sortedStrings(expected)) {
+			t.Errorf("expected the survivors to be kept, got %d values", len(got))
+		}
+	}
+}
+
+func TestBKTree_RemoveBulk_Frozen(t *testing.T) {
+	// 10% and 65% unlink the removed subtrees, 75% rebuilds the tree
+	for _, cut := range []uint64{2, 13, 15} {
+		hashes, tree := makeRandomHammingTree(1000, 6)
+		child, _ := largestChild(tree)
+		tree.FreezeSubtree(child.MetricTensor)
+		pred := func(val MetricTensor) bool { return uint64(val.(Hamming64))%20 < cut }
+		expected := make([]MetricTensor, 0, len(hashes))
+		for _, h := range hashes {
+			if !pred(h) {
+				expected = append(expected, h)
+			}
+		}
+		if removed := tree.RemoveBulk(pred); removed != len(hashes)-len(expected) {
+			t.Errorf("%d/20: expected: %d, got: %d", cut, len(hashes)-len(expected), removed)
+		}
+		if tree.Size != len(expected) || tree.CountDistinct() != len(expected) {
+			t.Errorf("%d/20: expected size: %d, got: %d (%d nodes)", cut, len(expected), tree.Size, tree.CountDistinct())
+		}
+		if got := sortedStrings(slices.Collect(tree.All())); !reflect.DeepEqual(got, sortedStrings(expected)) {
+			t.Errorf("%d/20: expected the survivors to be kept, got %d values", cut, len(got))
+		}
+		for _, q := range hashes[:20] {
+			got, _ := tree.Search(q, 14)
+			for _, v := range got {
+				if pred(v) {
+					t.Errorf("%d/20: expected %v to be removed", cut, v)
+				}
+			}
+			if all, _ := tree.SearchExhaustive(q, 14); !reflect.DeepEqual(sortedStrings(got), sortedStrings(all)) {
+				t.Errorf("%d/20: expected %d results, got: %d", cut, len(all), len(got))
+			}
+		}
+	}
+}
+
+func benchmarkRemoveBulk(b *testing.B, pred func(MetricTensor) bool) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		_, tree := makeRandomHammingTree(20000, 5)
+		b.StartTimer()
+		tree.RemoveBulk(pred)
+	}
+}
+
+func BenchmarkBKTree_RemoveBulk_10(b *testing.B) {
+	benchmarkRemoveBulk(b, func(val MetricTensor) bool { return uint64(val.(Hamming64))%10 == 0 })
+}
+
+func BenchmarkBKTree_RemoveBulk_50(b *testing.B) {
+	benchmarkRemoveBulk(b, func(val MetricTensor) bool { return uint64(val.(Hamming64))%2 == 0 })
+}
+
+func BenchmarkBKTree_RemoveBulk_90(b *testing.B) {
+	benchmarkRemoveBulk(b, func(val MetricTensor) bool { return uint64(val.(Hamming64))%10 != 0 })
+}
+
+func TestBKTree_RetainTopK(t *testing.T) {
+	tree := BuildFromWeighted(map[string]int{"some": 5, "soft": 1, "sorted": 8, "same": 3, "mole": 2}, func(s string) MetricTensor { return Word(s) })
+	if removed := tree.RetainTopK(3); removed != 2 {
+		t.Errorf("expected: %d, got: %d", 2, removed)
+	}
+	if got := sortedStrings(slices.Collect(tree.All())); !reflect.DeepEqual(got, []string{"same", "some", "sorted"}) {
+		t.Errorf("expected: [same some sorted], got: %v", got)
+	}
+	if tree.Size != 3 || tree.Count(Word("sorted")) != 8 || tree.Validate() != nil {
+		t.Errorf("expected a valid tree of 3 values keeping their counts, got size %d", tree.Size)
+	}
+	if removed := tree.RetainTopK(5); removed != 0 || tree.Size != 3 {
+		t.Errorf("expected nothing to be removed, got: %d", removed)
+	}
+	if removed := tree.RetainTopK(0); removed != 3 || tree.Root != nil || tree.Size != 0 {
+		t.Errorf("expected an empty tree, got: %d removed, size %d", removed, tree.Size)
+	}
+}
+
+func TestBKTree_RetainTopK_Ties(t *testing.T) {
+	words := []string{"sorted", "some", "soft", "same", "mole", "soda"}
+	for i := 0; i < 20; i++ {
+		tree := &BKTree{Sequence: true}
+		for _, w := range words {
+			tree.Add(Word(w))
+		}
+		tree.Add(Word("mole"))
+		tree.RetainTopK(3)
+		if got := sortedStrings(slices.Collect(tree.All())); !reflect.DeepEqual(got, []string{"mole", "some", "sorted"}) {
+			t.Fatalf("expected the most added value and the first added ones, got: %v", got)
+		}
+		unnumbered := createNewTreeFromWords(words)
+		unnumbered.RetainTopK(2)
+		if got := sortedStrings(slices.Collect(unnumbered.All())); !reflect.DeepEqual(got, []string{"mole", "same"}) {
+			t.Fatalf("expected ties to be broken by ToString without Sequence, got: %v", got)
+		}
+	}
+}
@@ -0,0 +1,701 @@
+package go_bk_tree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SearchApprox is a best-effort variant of Search that explores at most budget
+// children per visited node, preferring the ones whose bucket distance is closest
+// to the distance between the query and the node (those are the most likely to
+// hold matches). A small budget bounds the work done by each query at the cost
+// of recall: matches living in the skipped subtrees are never found. Given the
+// same tree and budget the result is deterministic, ties are broken by the
+// smaller bucket distance. A budget <= 0 means no limit, which is equal to Search.
+func (tree *BKTree) SearchApprox(val MetricTensor, radius Distance, budget int) ([]MetricTensor, int) {
+	count := 0
+	results := make([]MetricTensor, 0, 5)
+	if tree.Root == nil || val == nil {
+		return results, count
+	}
+	val = tree.normalize(val)
+	candidates := make([]*BkTreeNode, 0, 10)
+	candidates = append(candidates, tree.Root)
+	window := make([]Distance, 0, 10)
+	for len(candidates) > 0 {
+		cand := candidates[0]
+		candidates = candidates[1:]
+		if cand.MetricTensor == nil {
+			candidates = appendChildren(candidates, cand)
+			continue
+		}
+		dist := cand.DistanceFrom(val)
+		count += 1
+		if dist <= radius {
+			results = append(results, cand.MetricTensor)
+			if tree.capped(len(results)) {
+				break
+			}
+		}
+		low, high := searchWindow(dist, radius)
+		window = window[:0]
+		for d := range cand.Children {
+			if d >= low && d <= high {
+				window = append(window, d)
+			}
+		}
+		sort.Slice(window, func(i, j int) bool {
+			di, dj := diffDistance(window[i], dist), diffDistance(window[j], dist)
+			if di != dj {
+				return di < dj
+			}
+			return window[i] < window[j]
+		})
+		if budget > 0 && len(window) > budget {
+			window = window[:budget]
+		}
+		for _, d := range window {
+			candidates = append(candidates, cand.Children[d])
+		}
+	}
+	return results, count
+}
+
+// SearchExhaustive visits every node of the tree and returns all values within radius
+// of val, ignoring the triangle inequality pruning Search relies on. It is much slower
+// but stays correct for distances that are not true metrics (e.g. Sequence, or cosine
+// distances), on which Search can miss matches. Use it for such metrics when recall
+// matters more than latency, or to measure what Search misses (see MetricCheck).
+// It runs the traversal of Search with an unbounded radius for pruning.
+func (tree *BKTree) SearchExhaustive(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	count := tree.traverse(val, maxDistance, tree.collectWithin(radius, &results))
+	return results, count
+}
+
+// MeasureRecall compares approx, the results of an approximate search for val within
+// radius (e.g. SearchApprox or SearchMaxDepth), with an exhaustive search ignoring
+// MaxResults, and returns the fraction of the true matches approx holds (1 when there
+// is none) together with the true matches it missed. Values are compared by ToString.
+// It visits the whole tree and is meant to tune approximation budgets offline, not
+// for the query path.
+func (tree *BKTree) MeasureRecall(val MetricTensor, radius Distance, approx []MetricTensor) (float64, []MetricTensor) {
+	found := make(map[string]bool, len(approx))
+	for _, v := range approx {
+		found[v.ToString()] = true
+	}
+	total := 0
+	var missed []MetricTensor
+	tree.traverse(val, maxDistance, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			total++
+			if !found[node.ToString()] {
+				missed = append(missed, node.MetricTensor)
+			}
+		}
+		return true
+	})
+	if total == 0 {
+		return 1, missed
+	}
+	return float64(total-len(missed)) / float64(total), missed
+}
+
+// collectWithin returns a traverse visitor appending the values within radius to
+// results, which stops the traversal once MaxResults are collected
+func (tree *BKTree) collectWithin(radius Distance, results *[]MetricTensor) func(node *BkTreeNode, dist Distance) bool {
+	return func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			*results = append(*results, node.MetricTensor)
+		}
+		return !tree.capped(len(*results))
+	}
+}
+
+// SearchExact returns the values exactly d away from val. Every such value is within
+// radius d of val, so the tree is pruned as a radius-d Search would.
+func (tree *BKTree) SearchExact(val MetricTensor, d Distance) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	count := tree.traverse(val, d, func(node *BkTreeNode, dist Distance) bool {
+		if dist == d {
+			results = append(results, node.MetricTensor)
+		}
+		return !tree.capped(len(results))
+	})
+	return results, count
+}
+
+// SearchChecked works like Search but fails with ErrNegativeDistance as soon as the
+// metric returns an invalid distance (e.g. InvalidDistance for NaN), which would
+// otherwise make the pruning skip arbitrary subtrees. It fails with ErrEmptyTree when
+// there is nothing to search, so that no match can be told apart from no index. With
+// CheckDeterminism set it also fails with ErrNondeterministicMetric.
+func (tree *BKTree) SearchChecked(val MetricTensor, radius Distance) ([]MetricTensor, int, error) {
+	if tree.Root == nil {
+		return nil, 0, ErrEmptyTree
+	}
+	var err error
+	results := make([]MetricTensor, 0, 5)
+	if val == nil {
+		return results, 0, nil
+	}
+	val = tree.normalize(val)
+	distance := func(node *BkTreeNode) Distance {
+		dist, derr := tree.distance(node, val)
+		if derr != nil && err == nil {
+			err = derr
+		}
+		return dist
+	}
+	count := tree.traverseNodes(distance, radius, func(node *BkTreeNode, dist Distance) bool {
+		if err != nil {
+			return false
+		}
+		if dist < 0 {
+			err = fmt.Errorf("%w: %d between %s and %s", ErrNegativeDistance, dist, node.ToString(), val.ToString())
+			return false
+		}
+		if dist <= radius {
+			results = append(results, node.MetricTensor)
+		}
+		return !tree.capped(len(results))
+	})
+	if err != nil {
+		return nil, count, err
+	}
+	return results, count, nil
+}
+
+// AnyCloserThan returns the first value found strictly closer than t to val, and its
+// distance. The search prunes as a Search for radius t-1 and stops at the first match.
+func (tree *BKTree) AnyCloserThan(val MetricTensor, t Distance) (MetricTensor, Distance, bool) {
+	var found *BkTreeNode
+	var foundDist Distance
+	if t <= 0 {
+		return nil, 0, false
+	}
+	tree.traverse(val, t-1, func(node *BkTreeNode, dist Distance) bool {
+		if dist < t {
+			found, foundDist = node, dist
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, 0, false
+	}
+	return found.MetricTensor, foundDist, true
+}
+
+// SearchWithNearestMiss works like Search and also returns the closest visited value
+// that fell outside radius, with its distance, as a hint of how much to widen it. Its
+// Value is nil if every visited value matched. Only the visited nodes are considered:
+// a pruned subtree may hold a closer miss, so the miss is an upper bound of the
+// distance of the closest value outside radius, not necessarily that value.
+func (tree *BKTree) SearchWithNearestMiss(val MetricTensor, radius Distance) ([]MetricTensor, Match, int) {
+	results := make([]MetricTensor, 0, 5)
+	var miss Match
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			results = append(results, node.MetricTensor)
+		} else if miss.Value == nil || dist < miss.Distance {
+			miss = Match{node.MetricTensor, dist}
+		}
+		return !tree.capped(len(results))
+	})
+	return results, miss, count
+}
+
+// SearchMultiRadius runs a single traversal pruned with the largest of radii and
+// partitions the matches into bands aligned with radii: a value lands in the band of
+// the smallest radius it is within, so bands are disjoint and the values within
+// radii[i] are the union of the bands whose radius is at most radii[i]. Radii need
+// not be sorted, values beyond every radius are dropped.
+func (tree *BKTree) SearchMultiRadius(val MetricTensor, radii []Distance) ([][]MetricTensor, int) {
+	bands := make([][]MetricTensor, len(radii))
+	if len(radii) == 0 {
+		return bands, 0
+	}
+	order := make([]int, len(radii))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return radii[order[i]] < radii[order[j]] })
+	largest := radii[order[len(order)-1]]
+	total := 0
+	count := tree.traverse(val, largest, func(node *BkTreeNode, dist Distance) bool {
+		i := sort.Search(len(order), func(i int) bool { return dist <= radii[order[i]] })
+		if i < len(order) {
+			bands[order[i]] = append(bands[order[i]], node.MetricTensor)
+			total++
+		}
+		return !tree.capped(total)
+	})
+	return bands, count
+}
+
+// Facet is a value found by SearchFaceted, Count is the number of times it was added
+// (counting the duplicates Add dropped)
+type Facet struct {
+	Value    MetricTensor
+	Count    int
+	Distance Distance
+}
+
+// SearchFaceted works like Search but returns every match with its insertion count,
+// ranked by descending Count then ascending Distance, e.g. for "did you mean"
+// suggestions weighted by popularity. Unlike Search it collapses the matches by ResultKey
+// before MaxResults applies.
+func (tree *BKTree) SearchFaceted(val MetricTensor, radius Distance) ([]Facet, int) {
+	facets := make([]Facet, 0, 5)
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			facets = append(facets, Facet{node.MetricTensor, node.Duplicates + 1, dist})
+		}
+		return true
+	})
+	if tree.ResultKey != nil {
+		groups := make(map[string]int, len(facets))
+		collapsed := facets[:0]
+		for _, f := range facets {
+			k := tree.ResultKey(f.Value)
+			i, ok := groups[k]
+			if !ok {
+				groups[k] = len(collapsed)
+				collapsed = append(collapsed, f)
+				continue
+			}
+			if f.Distance < collapsed[i].Distance {
+				collapsed[i].Value, collapsed[i].Distance = f.Value, f.Distance
+			}
+			collapsed[i].Count += f.Count
+		}
+		facets = collapsed
+	}
+	sort.SliceStable(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Distance < facets[j].Distance
+	})
+	if tree.capped(len(facets)) {
+		facets = facets[:tree.MaxResults]
+	}
+	return facets, count
+}
+
+// SearchFilter works like Search but only returns the matches for which keep returns
+// true, keep being called on matches only. Filtering does not change the pruning: the
+// same nodes are visited as by Search, since a rejected value still bounds where the
+// matches below it can be. To stop at the first n kept matches, range over Matches
+// and break instead.
+func (tree *BKTree) SearchFilter(val MetricTensor, radius Distance, keep func(MetricTensor) bool) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius && keep(node.MetricTensor) {
+			results = append(results, node.MetricTensor)
+		}
+		return !tree.capped(len(results))
+	})
+	return results, count
+}
+
+// SearchRerank searches the tree for the values within radius of val under the tree's
+// metric, then re-scores these candidates with metric and returns those within
+// maxDistance of val under it, closest first.
+//
+// The tree is only ever pruned with its own metric, so metric can only rank the
+// candidate set, any value outside radius is never seen. Every match of metric is
+// found only when metric(val, v) <= maxDistance implies val.DistanceFrom(v) <= radius
+// for all values, e.g. when the tree's metric is at most k times metric and radius is
+// k*maxDistance. With a metric not bounded that way some matches are silently missed,
+// which is still fine to re-rank the results of a broad search. metric need not
+// satisfy the triangle inequality.
+func (tree *BKTree) SearchRerank(val MetricTensor, radius Distance, metric func(a, b MetricTensor) Distance, maxDistance Distance) ([]Match, int) {
+	matches := make([]Match, 0, 5)
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			if d := metric(val, node.MetricTensor); d <= maxDistance {
+				matches = append(matches, Match{Value: node.MetricTensor, Distance: d})
+			}
+		}
+		return true
+	})
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Distance < matches[j].Distance })
+	if tree.MaxResults > 0 && len(matches) > tree.MaxResults {
+		matches = matches[:tree.MaxResults]
+	}
+	return matches, count
+}
+
+// SortOrder selects how SearchSorted orders matches at the same distance
+type SortOrder int
+
+const (
+	// TiesInTraversalOrder keeps matches at the same distance in the order Search finds them
+	TiesInTraversalOrder SortOrder = iota
+	// TiesInInsertionOrder returns matches at the same distance in the order they were
+	// added, by Seq, which needs Sequence enabled. Values numbered before Sequence was
+	// enabled, or loaded from JSON, have no Seq and come first.
+	TiesInInsertionOrder
+)
+
+// SearchSorted works like Search but returns the matches with their distance, closest
+// first, breaking ties according to order, e.g. to return the same results in the
+// same order whatever the shape of the tree
+func (tree *BKTree) SearchSorted(val MetricTensor, radius Distance, order SortOrder) ([]Match, int) {
+	type seqMatch struct {
+		Match
+		seq uint64
+	}
+	matches := make([]seqMatch, 0, 5)
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			matches = append(matches, seqMatch{Match{node.MetricTensor, dist}, node.Seq})
+		}
+		return true
+	})
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance || order != TiesInInsertionOrder {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].seq < matches[j].seq
+	})
+	if tree.capped(len(matches)) {
+		matches = matches[:tree.MaxResults]
+	}
+	results := make([]Match, len(matches))
+	for i, m := range matches {
+		results[i] = m.Match
+	}
+	return results, count
+}
+
+// ScoredMatch is a value found by SearchScored with its distance and score
+type ScoredMatch struct {
+	Value    MetricTensor
+	Distance Distance
+	Score    float64
+}
+
+// SearchScored works like Search but returns every match with a confidence score,
+// highest first (closest first on ties). A nil scorer uses 1 - d/(radius+1), which
+// scores an exact match 1 and decreases linearly to 1/(radius+1) at radius, so that
+// scores are comparable between the results of one query but not across radii.
+func (tree *BKTree) SearchScored(val MetricTensor, radius Distance, scorer func(Distance) float64) ([]ScoredMatch, int) {
+	if scorer == nil {
+		scorer = func(d Distance) float64 { return 1 - float64(d)/(float64(radius)+1) }
+	}
+	matches := make([]ScoredMatch, 0, 5)
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist <= radius {
+			matches = append(matches, ScoredMatch{node.MetricTensor, dist, scorer(dist)})
+		}
+		return !tree.capped(len(matches))
+	})
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Distance < matches[j].Distance
+	})
+	return matches, count
+}
+
+// SearchUnion returns the values within radius of any of queries, each once, e.g. to
+// find the items similar to any of several examples. The tree is traversed once for
+// all queries: every candidate keeps the queries whose search window led to it, so a
+// distance is only computed for the queries that would have visited the node on their
+// own, and a subtree is skipped as soon as no query can reach it. This computes as
+// many distances as separate searches but walks the shared part of their traversals
+// once, with no union to deduplicate.
+func (tree *BKTree) SearchUnion(queries []MetricTensor, radius Distance) []MetricTensor {
+	results := make([]MetricTensor, 0, 5)
+	type candidate struct {
+		node    *BkTreeNode
+		queries []MetricTensor
+	}
+	active := make([]MetricTensor, 0, len(queries))
+	for _, q := range queries {
+		if q != nil {
+			active = append(active, tree.normalize(q))
+		}
+	}
+	if tree.Root == nil || len(active) == 0 {
+		return results
+	}
+	candidates := []candidate{{tree.Root, active}}
+	dists := make([]Distance, len(active))
+	for len(candidates) > 0 {
+		cand := candidates[0]
+		candidates = candidates[1:]
+		if cand.node.MetricTensor == nil {
+			for _, child := range cand.node.Children {
+				candidates = append(candidates, candidate{child, cand.queries})
+			}
+			continue
+		}
+		matched := false
+		for i, q := range cand.queries {
+			dists[i] = cand.node.DistanceFrom(q)
+			matched = matched || dists[i] <= radius
+		}
+		if matched {
+			results = append(results, cand.node.MetricTensor)
+			if tree.capped(len(results)) {
+				break
+			}
+		}
+		for d, child := range cand.node.Children {
+			var reaching []MetricTensor
+			for i, q := range cand.queries {
+				if diffDistance(dists[i], d) <= radius {
+					reaching = append(reaching, q)
+				}
+			}
+			if len(reaching) > 0 {
+				candidates = append(candidates, candidate{child, reaching})
+			}
+		}
+	}
+	return results
+}
+
+// SearchRadiusFunc works like Search with a radius depending on where values sit in
+// the tree: a value v matches when val.DistanceFrom(v) <= radiusFunc(d), d being the
+// distance between v and the root value (0 for the root itself), e.g. to widen the
+// search for values far from a reference value at the root whose distance units are
+// coarser there. Every value below the child bucket d of the root is exactly d away
+// from the root, so each such subtree is searched with the single radius
+// radiusFunc(d), which keeps the usual pruning exact, and is skipped at once when it
+// cannot hold a value within that radius. A negative radius matches nothing.
+func (tree *BKTree) SearchRadiusFunc(val MetricTensor, radiusFunc func(rootDist Distance) Distance) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	if tree.Root == nil || tree.Root.MetricTensor == nil || val == nil {
+		return results, 0
+	}
+	val = tree.normalize(val)
+	distance := func(node *BkTreeNode) Distance { return node.DistanceFrom(val) }
+	rootDist := tree.Root.DistanceFrom(val)
+	count := 1
+	if rootDist <= radiusFunc(0) {
+		results = append(results, tree.Root.MetricTensor)
+	}
+	for _, d := range tree.Root.sortedBuckets() {
+		if tree.capped(len(results)) {
+			break
+		}
+		radius := radiusFunc(d)
+		if radius < 0 || diffDistance(rootDist, d) > radius {
+			continue
+		}
+		subtree := &BKTree{Root: tree.Root.Children[d]}
+		count += subtree.traverseNodes(distance, radius, tree.collectWithin(radius, &results))
+	}
+	return results, count
+}
+
+// SearchMaxDepth works like Search but does not descend past maxDepth levels below the
+// root (the root is at depth 0), trading recall for speed on deep trees. The returned
+// flag reports whether some subtree that may hold matches was cut off by the limit.
+func (tree *BKTree) SearchMaxDepth(val MetricTensor, radius Distance, maxDepth int) ([]MetricTensor, int, bool) {
+	type candidate struct {
+		node  *BkTreeNode
+		depth int
+	}
+	count := 0
+	truncated := false
+	results := make([]MetricTensor, 0, 5)
+	if tree.Root == nil || val == nil || maxDepth < 0 {
+		return results, count, tree.Root != nil && val != nil
+	}
+	val = tree.normalize(val)
+	candidates := []candidate{{tree.Root, 0}}
+	for len(candidates) > 0 {
+		cand := candidates[0]
+		candidates = candidates[1:]
+		if cand.node.MetricTensor == nil {
+			// the children of a nil value stay at its depth, it is not a level of the search
+			for _, child := range cand.node.Children {
+				candidates = append(candidates, candidate{child, cand.depth})
+			}
+			continue
+		}
+		dist := cand.node.DistanceFrom(val)
+		count += 1
+		if dist <= radius {
+			results = append(results, cand.node.MetricTensor)
+			if tree.capped(len(results)) {
+				break
+			}
+		}
+		low, high := searchWindow(dist, radius)
+		for d, child := range cand.node.Children {
+			if d >= low && d <= high {
+				if cand.depth == maxDepth {
+					truncated = true
+					continue
+				}
+				candidates = append(candidates, candidate{child, cand.depth + 1})
+			}
+		}
+	}
+	return results, count, truncated
+}
+
+// progressInterval is the number of visited nodes between two SearchWithProgress reports
+const progressInterval = 1024
+
+// SearchWithProgress works like Search and calls progress with the number of nodes
+// visited so far every 1024 visited nodes, and once more at the end of the search
+func (tree *BKTree) SearchWithProgress(val MetricTensor, radius Distance, progress func(visited int)) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	visited := 0
+	collect := tree.collectWithin(radius, &results)
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		more := collect(node, dist)
+		if visited += 1; visited%progressInterval == 0 {
+			progress(visited)
+		}
+		return more
+	})
+	progress(count)
+	return results, count
+}
+
+// traverse visits the nodes a Search for radius would visit, in the same order, and
+// calls visit with each of them and its distance from val. Returning false from visit
+// stops the traversal. It returns the number of visited nodes.
+func (tree *BKTree) traverse(val MetricTensor, radius Distance, visit func(node *BkTreeNode, dist Distance) bool) int {
+	if tree.Root == nil || val == nil {
+		return 0
+	}
+	val = tree.normalize(val)
+	return tree.traverseNodes(func(node *BkTreeNode) Distance { return node.DistanceFrom(val) }, radius, visit)
+}
+
+// traverseNodes works like traverse with the distance between the query and a node
+// computed by distance
+func (tree *BKTree) traverseNodes(distance func(node *BkTreeNode) Distance, radius Distance, visit func(node *BkTreeNode, dist Distance) bool) int {
+	count := 0
+	if tree.Root == nil {
+		return count
+	}
+	candidates := make([]*BkTreeNode, 0, 10)
+	candidates = append(candidates, tree.Root)
+	for len(candidates) > 0 {
+		cand := candidates[0]
+		candidates = candidates[1:]
+		if cand.MetricTensor == nil {
+			candidates = appendChildren(candidates, cand)
+			continue
+		}
+		dist := distance(cand)
+		count += 1
+		if !visit(cand, dist) {
+			break
+		}
+		low, high := searchWindow(dist, radius)
+		for d, child := range cand.Children {
+			if d >= low && d <= high {
+				candidates = append(candidates, child)
+			}
+		}
+	}
+	return count
+}
+
+// SearchDedup works like Search but collapses the results sharing the same key into
+// the one closest to val (the first one found on ties). A group keeps the position
+// of its first result in the traversal order.
+func (tree *BKTree) SearchDedup(val MetricTensor, radius Distance, key func(MetricTensor) string) ([]MetricTensor, int) {
+	results := make([]MetricTensor, 0, 5)
+	dists := make([]Distance, 0, 5)
+	groups := make(map[string]int)
+	count := tree.traverse(val, radius, func(node *BkTreeNode, dist Distance) bool {
+		if dist > radius {
+			return true
+		}
+		k := key(node.MetricTensor)
+		if i, ok := groups[k]; ok {
+			if dist < dists[i] {
+				results[i], dists[i] = node.MetricTensor, dist
+			}
+			return true
+		}
+		groups[k] = len(results)
+		results = append(results, node.MetricTensor)
+		dists = append(dists, dist)
+		return !tree.capped(len(results))
+	})
+	return results, count
+}
+
+// SearchTargetCount looks for about targetN values close to val without a known radius:
+// it searches with a radius of 0 and doubles it until at least targetN values are found
+// or the whole tree is within the radius. It returns the matches sorted by distance, the
+// smallest radius yielding at least targetN of them (all the values at that distance are
+// included, so there may be more than targetN), and the number of distance computations.
+// Distances are memoized across rounds so each node's distance is computed once, but
+// the nodes within the radius are traversed again on every round: the worst case is
+// O(log(max distance)) traversals of the whole tree, for n distance computations.
+func (tree *BKTree) SearchTargetCount(val MetricTensor, targetN int) ([]MetricTensor, Distance, int) {
+	results := make([]MetricTensor, 0, 5)
+	if tree.Root == nil || val == nil || targetN <= 0 {
+		return results, 0, 0
+	}
+	val = tree.normalize(val)
+	known := make(map[*BkTreeNode]Distance)
+	var matches []Match
+	radius := Distance(0)
+	for {
+		matches = matches[:0]
+		// missed reports a value out of the radius, visited or pruned
+		missed := false
+		candidates := []*BkTreeNode{tree.Root}
+		for len(candidates) > 0 {
+			cand := candidates[len(candidates)-1]
+			candidates = candidates[:len(candidates)-1]
+			if cand.MetricTensor == nil {
+				candidates = appendChildren(candidates, cand)
+				continue
+			}
+			dist, ok := known[cand]
+			if !ok {
+				dist = cand.DistanceFrom(val)
+				known[cand] = dist
+			}
+			if dist <= radius {
+				matches = append(matches, Match{cand.MetricTensor, dist})
+			} else {
+				missed = true
+			}
+			low, high := searchWindow(dist, radius)
+			for d, child := range cand.Children {
+				if d >= low && d <= high {
+					candidates = append(candidates, child)
+				} else {
+					missed = true
+				}
+			}
+		}
+		if len(matches) >= targetN || !missed || radius == maxDistance {
+			break
+		}
+		radius = addDistance(addDistance(radius, radius), 1)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Distance < matches[j].Distance })
+	if len(matches) >= targetN {
+		// every value within a smaller radius was found as well
+		radius = matches[targetN-1].Distance
+	} else if len(matches) > 0 {
+		radius = matches[len(matches)-1].Distance
+	}
+	for _, m := range matches {
+		if m.Distance > radius || tree.capped(len(results)) {
+			break
+		}
+		results = append(results, m.Value)
+	}
+	return results, radius, len(known)
+}
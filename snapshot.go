@@ -0,0 +1,71 @@
+package go_bk_tree
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FrozenSnapshot holds the current FrozenTree of a long-running service, which is
+// rebuilt and swapped with Refresh while readers keep using the previous one. A
+// reader calling Load gets a snapshot that stays consistent for as long as it holds
+// it. The zero value holds an empty tree and is ready to use.
+type FrozenSnapshot struct {
+	refreshing sync.Mutex
+	current    atomic.Pointer[FrozenTree]
+	// started numbers the refreshes as they start, stored is the number of the one
+	// whose tree is current (guarded by refreshing)
+	started atomic.Uint64
+	stored  uint64
+}
+
+// Load returns the current frozen tree, never nil
+func (s *FrozenSnapshot) Load() *FrozenTree {
+	if ft := s.current.Load(); ft != nil {
+		return ft
+	}
+	return new(FrozenTree)
+}
+
+// Search runs a search on the current frozen tree
+func (s *FrozenSnapshot) Search(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	return s.Load().Search(val, radius)
+}
+
+// Refresh calls build, freezes the tree it returns and swaps it in. Concurrent
+// refreshes are serialized and numbered as they start, the last one to start is the
+// one kept: a refresh getting its turn after a later one was stored returns without
+// calling build. A nil tree returned by build (e.g. when loading failed) leaves the
+// current snapshot in place. The built tree is not retained and may be reused by
+// build once Refresh returns.
+func (s *FrozenSnapshot) Refresh(build func() *BKTree) {
+	gen := s.started.Add(1)
+	s.refreshing.Lock()
+	defer s.refreshing.Unlock()
+	if gen < s.stored {
+		return
+	}
+	tree := build()
+	if tree == nil {
+		return
+	}
+	s.current.Store(tree.FreezeParallel())
+	s.stored = gen
+}
+
+// RefreshEvery refreshes the snapshot right away and then every interval until ctx
+// is done, it blocks and is meant to run in its own goroutine
+func (s *FrozenSnapshot) RefreshEvery(ctx context.Context, interval time.Duration, build func() *BKTree) {
+	s.Refresh(build)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Refresh(build)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package go_bk_tree
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFrozenSnapshot_Refresh(t *testing.T) {
+	var s FrozenSnapshot
+	if results, _ := s.Search(Word("some"), 1); len(results) != 0 {
+		t.Errorf("expected no results, got: %v", results)
+	}
+	s.Refresh(func() *BKTree { return createNewTreeFromWords([]string{"some", "soft"}) })
+	old := s.Load()
+	s.Refresh(func() *BKTree { return createNewTreeFromWords([]string{"same", "mole", "sole"}) })
+	if old.Size != 2 || s.Load().Size != 3 {
+		t.Errorf("expected sizes 2 and 3, got: %d and %d", old.Size, s.Load().Size)
+	}
+	if results, _ := old.Search(Word("some"), 0); len(results) != 1 {
+		t.Errorf("expected the old snapshot to be unchanged, got: %v", results)
+	}
+}
+
+func TestFrozenSnapshot_RefreshEvery(t *testing.T) {
+	var s FrozenSnapshot
+	var mu sync.Mutex
+	builds := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.RefreshEvery(ctx, time.Millisecond, func() *BKTree {
+			mu.Lock()
+			defer mu.Unlock()
+			builds++
+			return createNewTreeFromWords([]string{"some"})
+		})
+	}()
+	for i := 0; i < 1000 && s.Load().Size == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if s.Load().Size != 1 || builds == 0 {
+		t.Errorf("expected a refreshed snapshot, got size: %d after %d builds", s.Load().Size, builds)
+	}
+}
+
+func TestFrozenSnapshot_Refresh_LastStartedWins(t *testing.T) {
+	var s FrozenSnapshot
+	s.refreshing.Lock()
+	var wg sync.WaitGroup
+	for i, words := range [][]string{{"some"}, {"some", "soft"}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Refresh(func() *BKTree { return createNewTreeFromWords(words) })
+		}()
+		for s.started.Load() != uint64(i+1) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	s.refreshing.Unlock()
+	wg.Wait()
+	if s.Load().Size != 2 {
+		t.Errorf("expected the last refresh to start to be kept, got size %d", s.Load().Size)
+	}
+	s.Refresh(func() *BKTree { return nil })
+	if s.Load().Size != 2 {
+		t.Errorf("expected a nil tree to keep the snapshot, got size %d", s.Load().Size)
+	}
+}
@@ -0,0 +1,138 @@
+package go_bk_tree
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NodeID identifies a node in a NodeStore, nodes are numbered from 0 (the root)
+// in insertion order
+type NodeID uint64
+
+// StoredNode is a node as kept in a NodeStore, children are referenced by ID
+type StoredNode struct {
+	Value    MetricTensor
+	Children map[Distance]NodeID
+}
+
+// NodeStore abstracts the storage of the nodes of a StoredTree, so that they can be
+// paged from disk (e.g. a key-value store keyed by NodeID, the value serialized by
+// its ToString) instead of all held in memory. Get returns an error for a missing ID.
+type NodeStore interface {
+	Get(id NodeID) (StoredNode, error)
+	Put(id NodeID, node StoredNode) error
+}
+
+// MemoryStore is the in-memory NodeStore, it is safe for concurrent use
+type MemoryStore struct {
+	mu    sync.RWMutex
+	nodes map[NodeID]StoredNode
+}
+
+func (s *MemoryStore) Get(id NodeID) (StoredNode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	node, ok := s.nodes[id]
+	if !ok {
+		return StoredNode{}, fmt.Errorf("go_bk_tree: node %d not found", id)
+	}
+	return node, nil
+}
+
+func (s *MemoryStore) Put(id NodeID, node StoredNode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nodes == nil {
+		s.nodes = make(map[NodeID]StoredNode)
+	}
+	s.nodes[id] = node
+	return nil
+}
+
+// StoredTree is a BK-tree whose nodes live in a NodeStore and are fetched one by
+// one while descending, so only the visited nodes need to be in memory. A tree is
+// reopened over an existing store by setting Size, the IDs in use being 0 to Size-1.
+// Unlike BKTree, every operation can fail with the error of the store.
+type StoredTree struct {
+	Size  int
+	Store NodeStore
+}
+
+// Add a value as BKTree.Add does, the new node and its parent are written to the store
+func (tree *StoredTree) Add(val MetricTensor) error {
+	if val == nil {
+		return ErrNilValue
+	}
+	newNode := StoredNode{Value: val, Children: make(map[Distance]NodeID)}
+	if tree.Size == 0 {
+		if err := tree.Store.Put(0, newNode); err != nil {
+			return err
+		}
+		tree.Size = 1
+		return nil
+	}
+	id := NodeID(0)
+	for {
+		node, err := tree.Store.Get(id)
+		if err != nil {
+			return err
+		}
+		dist := node.Value.DistanceFrom(val)
+		if dist < 0 {
+			return fmt.Errorf("%w: %d between %s and %s", ErrNegativeDistance, dist, node.Value.ToString(), val.ToString())
+		}
+		if dist == 0 {
+			return nil
+		}
+		child, ok := node.Children[dist]
+		if !ok {
+			newID := NodeID(tree.Size)
+			if err := tree.Store.Put(newID, newNode); err != nil {
+				return err
+			}
+			// the store may hand out its own map, never modify it in place
+			children := make(map[Distance]NodeID, len(node.Children)+1)
+			for d, c := range node.Children {
+				children[d] = c
+			}
+			children[dist] = newID
+			node.Children = children
+			if err := tree.Store.Put(id, node); err != nil {
+				return err
+			}
+			tree.Size += 1
+			return nil
+		}
+		id = child
+	}
+}
+
+// Search works like BKTree.Search, the children in the window of a node are
+// fetched from the store only when they are visited
+func (tree *StoredTree) Search(val MetricTensor, radius Distance) ([]MetricTensor, int, error) {
+	results := make([]MetricTensor, 0, 5)
+	if tree.Size == 0 {
+		return results, 0, nil
+	}
+	count := 0
+	candidates := []NodeID{0}
+	for len(candidates) > 0 {
+		node, err := tree.Store.Get(candidates[0])
+		if err != nil {
+			return nil, count, err
+		}
+		candidates = candidates[1:]
+		dist := node.Value.DistanceFrom(val)
+		count += 1
+		if dist <= radius {
+			results = append(results, node.Value)
+		}
+		low, high := searchWindow(dist, radius)
+		for d, child := range node.Children {
+			if d >= low && d <= high {
+				candidates = append(candidates, child)
+			}
+		}
+	}
+	return results, count, nil
+}
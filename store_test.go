@@ -0,0 +1,48 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// failingStore fails every Get once broken
+type failingStore struct {
+	MemoryStore
+	broken bool
+}
+
+var errStore = errors.New("store unavailable")
+
+func (s *failingStore) Get(id NodeID) (StoredNode, error) {
+	if s.broken {
+		return StoredNode{}, errStore
+	}
+	return s.MemoryStore.Get(id)
+}
+
+func TestStoredTree_Search(t *testing.T) {
+	hashes, tree := makeRandomHammingTree(1000, 12)
+	store := new(failingStore)
+	stored := &StoredTree{Store: store}
+	for _, h := range hashes {
+		if err := stored.Add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reopened := &StoredTree{Store: store, Size: stored.Size}
+	for _, q := range hashes[:20] {
+		expected, expectedCount := tree.Search(q, 16)
+		got, count, err := reopened.Search(q, 16)
+		if err != nil || count != expectedCount || !reflect.DeepEqual(sortedStrings(expected), sortedStrings(got)) {
+			t.Errorf("expected: %v, got: %v (%v)", sortedStrings(expected), sortedStrings(got), err)
+		}
+	}
+	store.broken = true
+	if _, _, err := reopened.Search(hashes[0], 1); !errors.Is(err, errStore) {
+		t.Errorf("expected: %v, got: %v", errStore, err)
+	}
+	if err := reopened.Add(hashes[0]); !errors.Is(err, errStore) {
+		t.Errorf("expected: %v, got: %v", errStore, err)
+	}
+}
@@ -0,0 +1,122 @@
+package go_bk_tree
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// StructMetric compares structs field by field through reflection, to index any
+// struct without writing a MetricTensor while prototyping. The distance between two
+// structs is the weighted sum of the distances of their exported fields: the edit
+// distance for strings (see EditString), the absolute difference for numbers (rounded
+// for floats), 0 or 1 for booleans and for any other kind (equal or not by
+// reflect.DeepEqual), and the same sum over the fields of nested structs. It is a
+// metric as long as the weights are not negative.
+//
+// Reflection adds a field lookup and a type switch per field to every distance, and
+// the string fields are copied to runes: write a MetricTensor for production use.
+//
+// Example:
+//
+//	m := &StructMetric{Weights: map[string]Distance{"Name": 2, "Address.City": 1, "ID": 0}}
+//	tree.Add(m.Wrap(person))
+type StructMetric struct {
+	// Weights multiplies the distance of the fields by name, using dotted names for
+	// the fields of nested structs. A field missing from Weights weighs 1 and a field
+	// weighing 0 is ignored.
+	Weights map[string]Distance
+}
+
+// Wrap returns v, a struct or a pointer to one, as a MetricTensor that can be compared
+// with the values of the same type wrapped by the same StructMetric. It panics if v is
+// not a struct.
+func (m *StructMetric) Wrap(v any) MetricTensor {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("go_bk_tree: StructMetric.Wrap of a %T", v))
+	}
+	return structValue{m, value}
+}
+
+// Unwrap returns the struct held by a value returned by Wrap
+func (m *StructMetric) Unwrap(val MetricTensor) any {
+	return val.(structValue).value.Interface()
+}
+
+type structValue struct {
+	metric *StructMetric
+	value  reflect.Value
+}
+
+func (s structValue) DistanceFrom(other MetricTensor) Distance {
+	return s.metric.structDistance(s.value, other.(structValue).value, "")
+}
+
+func (s structValue) ToString() string {
+	return fmt.Sprintf("%+v", s.value.Interface())
+}
+
+func (m *StructMetric) structDistance(a, b reflect.Value, prefix string) Distance {
+	var sum Distance
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + field.Name
+		weight, ok := m.Weights[name]
+		if !ok {
+			weight = 1
+		}
+		if weight == 0 {
+			continue
+		}
+		d := m.fieldDistance(a.Field(i), b.Field(i), name)
+		if d > maxDistance/weight {
+			return maxDistance
+		}
+		sum = addDistance(sum, d*weight)
+	}
+	return sum
+}
+
+func (m *StructMetric) fieldDistance(a, b reflect.Value, name string) Distance {
+	switch a.Kind() {
+	case reflect.String:
+		return EditString(a.String()).DistanceFrom(EditString(b.String()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, y := a.Int(), b.Int()
+		if x < y {
+			x, y = y, x
+		}
+		return saturate(uint64(x) - uint64(y))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, y := a.Uint(), b.Uint()
+		if x < y {
+			x, y = y, x
+		}
+		return saturate(x - y)
+	case reflect.Float32, reflect.Float64:
+		return DistanceFromFloat(math.Abs(a.Float() - b.Float()))
+	case reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0
+		}
+		return 1
+	case reflect.Struct:
+		return m.structDistance(a, b, name+".")
+	}
+	if reflect.DeepEqual(a.Interface(), b.Interface()) {
+		return 0
+	}
+	return 1
+}
+
+// saturate converts an unsigned difference to a Distance, clamped to the largest one
+func saturate(d uint64) Distance {
+	if d > uint64(maxDistance) {
+		return maxDistance
+	}
+	return Distance(d)
+}
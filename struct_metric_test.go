@@ -0,0 +1,53 @@
+package go_bk_tree
+
+import (
+	"math"
+	"testing"
+)
+
+type address struct {
+	City string
+	Zip  int
+}
+
+type person struct {
+	ID      int
+	Name    string
+	Age     uint8
+	Height  float64
+	Member  bool
+	Tags    []string
+	Address address
+	secret  string
+}
+
+func TestStructMetric(t *testing.T) {
+	m := &StructMetric{Weights: map[string]Distance{"ID": 0, "Name": 2, "Address.Zip": 0}}
+	a := person{ID: 1, Name: "alice", Age: 30, Height: 1.7, Tags: []string{"x"}, Address: address{"paris", 75001}, secret: "a"}
+	b := person{ID: 2, Name: "alina", Age: 33, Height: 1.2, Member: true, Tags: []string{"y"}, Address: address{"pari", 75002}, secret: "b"}
+	// name 2*2, age 3, height 0.5 rounded, member 1, tags 1, city 1
+	if d := m.Wrap(a).DistanceFrom(m.Wrap(&b)); d != 4+3+1+1+1+1 {
+		t.Errorf("expected: %d, got: %d", 11, d)
+	}
+	if d := m.Wrap(a).DistanceFrom(m.Wrap(a)); d != 0 {
+		t.Errorf("expected: %d, got: %d", 0, d)
+	}
+	if d := (&StructMetric{}).Wrap(person{ID: math.MinInt}).DistanceFrom((&StructMetric{}).Wrap(person{ID: math.MaxInt})); d != maxDistance {
+		t.Errorf("expected the difference to saturate, got: %d", d)
+	}
+
+	tree := new(BKTree)
+	for _, name := range []string{"alice", "alina", "bob", "carol"} {
+		tree.Add(m.Wrap(person{Name: name, Address: address{City: "paris"}}))
+	}
+	results, _ := tree.Search(m.Wrap(person{Name: "alicx", Address: address{City: "paris"}}), 2)
+	if len(results) != 1 || m.Unwrap(results[0]).(person).Name != "alice" {
+		t.Errorf("expected alice, got: %v", results)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected wrapping a non-struct to panic")
+		}
+	}()
+	m.Wrap(42)
+}
@@ -0,0 +1,104 @@
+package go_bk_tree
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Subtree returns a tree rooted at the node holding val, with its Size recomputed.
+// The subtree of a node is itself a valid BK-tree. When clone is false the returned
+// tree shares its nodes with this one and must be treated as read-only: a value added
+// through it would break the invariant of this tree's ancestors of the subtree. When
+// clone is true the nodes are deep-copied first and both trees are independent.
+// It returns false if val is not in the tree.
+func (tree *BKTree) Subtree(val MetricTensor, clone bool) (*BKTree, bool) {
+	node := tree.Find(val)
+	if node == nil {
+		return nil, false
+	}
+	if clone {
+		node = node.clone()
+	}
+	subtree := &BKTree{Root: node}
+	subtree.CalculateSize()
+	return subtree, true
+}
+
+// RebuildSubtree reorganizes the subtree rooted at the node holding val (found as
+// Find does): its node stays in place and the values below it are reinserted under
+// it in an order shuffled with rng, which undoes the long chains left by sorted or
+// adversarial insertion orders. Pass a *rand.Rand to make the shuffle reproducible,
+// a nil rng falls back to a time-seeded source. The rest of the tree and the node
+// metadata are untouched, and Size only changes when values within Epsilon of each
+// other end up merged as duplicates. Nodes holding a nil value are dropped. It costs
+// one descent from the subtree root per value, and returns false if val is not in the
+// tree.
+//
+// Nothing, at Add time or later, can shorten the chains of clustered values: a value
+// only fits the bucket of its distance from every ancestor and any value of a subtree
+// can root it, so the only freedom is the order, and values all the same distance
+// apart (e.g. the hashes one bit away from a common center, 2 apart pairwise) form a
+// chain in any order. On such clusters (BenchmarkBKTree_RebuildSubtree_Clustered) the
+// height stays 59 after rebuilding the whole tree.
+func (tree *BKTree) RebuildSubtree(val MetricTensor, rng *rand.Rand) bool {
+	node, covering := tree.findFrozen(val)
+	if node == nil {
+		return false
+	}
+	if covering != nil {
+		covering.frozen = nil
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	var orphans []*BkTreeNode
+	for _, child := range node.Children {
+		orphans = collectNodes(child, orphans)
+	}
+	// the nodes holding a nil value are dropped, as Repair does
+	orphans = slices.DeleteFunc(orphans, func(o *BkTreeNode) bool { return o.MetricTensor == nil })
+	// sort first so that the shuffle only depends on rng, not on the map order
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].ToString() < orphans[j].ToString() })
+	rng.Shuffle(len(orphans), func(i, j int) { orphans[i], orphans[j] = orphans[j], orphans[i] })
+	node.Children = tree.newChildren()
+	subtree := &BKTree{Root: node, Size: 1, Epsilon: tree.Epsilon, ChildrenCap: tree.ChildrenCap}
+	subtree.reinsert(orphans)
+	tree.Size -= len(orphans) - (subtree.Size - 1)
+	tree.version++
+	return true
+}
+
+// clone deep-copies the subtree rooted at the node, values are not copied but metadata is
+func (node *BkTreeNode) clone() *BkTreeNode {
+	copied := *node
+	copied.Children = make(map[Distance]*BkTreeNode, len(node.Children))
+	for dist, child := range node.Children {
+		copied.Children[dist] = child.clone()
+	}
+	return &copied
+}
+
+// PathTo returns the values from the root down to the one holding val (found as
+// Find does), and the bucket distances linking each of them to the next, so there
+// is one distance less than values. It returns false if val is not in the tree.
+func (tree *BKTree) PathTo(val MetricTensor) ([]MetricTensor, []Distance, bool) {
+	if val == nil {
+		return nil, nil, false
+	}
+	val = tree.normalize(val)
+	var values []MetricTensor
+	var dists []Distance
+	curNode := tree.Root
+	for curNode != nil && curNode.MetricTensor != nil {
+		values = append(values, curNode.MetricTensor)
+		dist := curNode.DistanceFrom(val)
+		if dist <= tree.Epsilon {
+			return values, dists, true
+		}
+		dists = append(dists, dist)
+		curNode = curNode.Children[dist]
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,139 @@
+package go_bk_tree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBKTree_Subtree(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"a", "ab", "abc", "d"})
+	if _, ok := tree.Subtree(Word("x"), false); ok {
+		t.Error("expected x not to be found")
+	}
+	shared, ok := tree.Subtree(Word("abc"), false)
+	if !ok || shared.Size != 2 || shared.Root != tree.Root.Children[2] {
+		t.Fatalf("expected a shared subtree of size 2, got: %+v", shared)
+	}
+	cloned, _ := tree.Subtree(Word("abc"), true)
+	if cloned.Size != 2 || cloned.Root == shared.Root {
+		t.Fatalf("expected a cloned subtree of size 2, got: %+v", cloned)
+	}
+	cloned.Add(Word("abcd"))
+	if tree.Find(Word("abcd")) != nil {
+		t.Error("expected the cloned subtree to be independent")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBKTree_PathTo(t *testing.T) {
+	tree := createNewTreeFromWords([]string{"some", "soft", "same", "sole"})
+	values, dists, ok := tree.PathTo(Word("sole"))
+	if !ok {
+		t.Fatal("expected sole to be found")
+	}
+	if expected := []MetricTensor{Word("some"), Word("same"), Word("sole")}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected: %v, got: %v", expected, values)
+	}
+	if expected := []Distance{2, 4}; !reflect.DeepEqual(dists, expected) {
+		t.Errorf("expected: %v, got: %v", expected, dists)
+	}
+	if values, dists, ok := tree.PathTo(Word("some")); !ok || len(values) != 1 || len(dists) != 0 {
+		t.Errorf("expected the root alone, got: %v, %v, %v", values, dists, ok)
+	}
+	if _, _, ok := tree.PathTo(Word("mole")); ok {
+		t.Error("expected mole not to be found")
+	}
+}
+
+func TestBKTree_RebuildSubtree(t *testing.T) {
+	_, tree := makeRandomHammingTree(2000, 13)
+	var child *BkTreeNode
+	for _, c := range tree.Root.Children {
+		if child == nil || c.getSize() > child.getSize() {
+			child = c
+		}
+	}
+	var before []MetricTensor
+	for _, n := range collectNodes(child, nil) {
+		before = append(before, n.MetricTensor)
+	}
+	size := tree.Size
+	if !tree.RebuildSubtree(child.MetricTensor, rand.New(rand.NewSource(1))) {
+		t.Fatal("expected the child to be found")
+	}
+	var after []MetricTensor
+	for _, n := range collectNodes(tree.Root.Children[tree.Root.DistanceFrom(child.MetricTensor)], nil) {
+		after = append(after, n.MetricTensor)
+	}
+	if !reflect.DeepEqual(sortedStrings(before), sortedStrings(after)) {
+		t.Error("expected the values of the subtree to be preserved")
+	}
+	if tree.Size != size || tree.CountDistinct() != size {
+		t.Errorf("expected: %d, got: %d (%d counted)", size, tree.Size, tree.CountDistinct())
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+	if tree.RebuildSubtree(Hamming64(1), nil) {
+		t.Error("expected 1 not to be found")
+	}
+}
+
+// clusteredHashes returns size hashes around clusters random centers, each 1 to flips
+// random bits away from its center, grouped by cluster
+func clusteredHashes(size, clusters, flips int, seed int64) []Hamming64 {
+	r := rand.New(rand.NewSource(seed))
+	hashes := make([]Hamming64, 0, size)
+	for c := 0; c < clusters; c++ {
+		center := r.Uint64()
+		for i := 0; i < size/clusters; i++ {
+			h := center
+			for f := 0; f <= r.Intn(flips); f++ {
+				h ^= 1 << r.Intn(64)
+			}
+			hashes = append(hashes, Hamming64(h))
+		}
+	}
+	return hashes
+}
+
+func BenchmarkBKTree_RebuildSubtree_Clustered(b *testing.B) {
+	hashes := clusteredHashes(20000, 50, 2, 1)
+	var before, after int
+	for i := 0; i < b.N; i++ {
+		tree := new(BKTree)
+		for _, h := range hashes {
+			tree.Add(h)
+		}
+		before = height(tree.Root)
+		tree.RebuildSubtree(tree.Root.MetricTensor, nil)
+		after = height(tree.Root)
+	}
+	b.ReportMetric(float64(before), "height")
+	b.ReportMetric(float64(after), "height(rebuilt)")
+}
+
+func TestBKTree_RebuildSubtree_Reproducible(t *testing.T) {
+	_, a := makeRandomHammingTree(2000, 14)
+	_, b := makeRandomHammingTree(2000, 14)
+	a.RebuildSubtree(a.Root.MetricTensor, rand.New(rand.NewSource(7)))
+	b.RebuildSubtree(b.Root.MetricTensor, rand.New(rand.NewSource(7)))
+	ja, _ := a.ToJson()
+	jb, _ := b.ToJson()
+	if !reflect.DeepEqual(ja, jb) {
+		t.Error("expected subtrees rebuilt with the same seed to be identical")
+	}
+
+	tree := new(BKTree)
+	for _, h := range []Hamming64{0, 0b11, 0b101, 0b1001} {
+		tree.Add(h)
+	}
+	tree.Epsilon = 2
+	tree.RebuildSubtree(Hamming64(0), rand.New(rand.NewSource(1)))
+	if tree.Size != tree.CountDistinct() || tree.Size != 1 {
+		t.Errorf("expected the values within Epsilon to be merged, got size %d (%d counted)", tree.Size, tree.CountDistinct())
+	}
+}
@@ -0,0 +1,128 @@
+package go_bk_tree
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"sync"
+)
+
+// SyncBKTree wraps a BKTree with a read-write lock so that it can be shared by
+// concurrent writers and readers. The zero value is an empty tree ready to use.
+type SyncBKTree struct {
+	mu   sync.RWMutex
+	tree BKTree
+}
+
+// Add a value under the write lock
+func (st *SyncBKTree) Add(val MetricTensor) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Add(val)
+}
+
+// Search under the read lock
+func (st *SyncBKTree) Search(val MetricTensor, radius Distance) ([]MetricTensor, int) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	if st.tree.Root == nil {
+		return make([]MetricTensor, 0), 0
+	}
+	return st.tree.Search(val, radius)
+}
+
+// SearchVersioned works like Search and also returns the version of the tree the
+// results come from, see Version
+func (st *SyncBKTree) SearchVersioned(val MetricTensor, radius Distance) ([]MetricTensor, int, uint64) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	results, count := st.tree.Search(val, radius)
+	return results, count, st.tree.version
+}
+
+// Version returns the version of the tree, see BKTree.Version: results obtained with
+// SearchVersioned are stale when it returns another version
+func (st *SyncBKTree) Version() uint64 {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.version
+}
+
+// Size returns the number of indexed values
+func (st *SyncBKTree) Size() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Size
+}
+
+// All returns an iterator over every value that holds the read lock for the whole
+// loop, so it sees a consistent tree but blocks writers until the loop ends. The
+// loop body must not call any method of the same SyncBKTree, not even a read such as
+// Search or Size: a write would deadlock at once, and a read taking the read lock
+// again deadlocks as soon as a writer is waiting for it. Use Snapshot for loops that
+// call the tree, or that run long.
+func (st *SyncBKTree) All() iter.Seq[MetricTensor] {
+	return func(yield func(MetricTensor) bool) {
+		st.mu.RLock()
+		defer st.mu.RUnlock()
+		for v := range st.tree.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Snapshot copies the values under the read lock, which is released before the
+// first iteration: writers are only blocked while copying, at the cost of one
+// interface value (two words) per value held until the loop ends. The values are
+// those of the tree at the time of the call, whatever the writers do afterwards.
+func (st *SyncBKTree) Snapshot() iter.Seq[MetricTensor] {
+	st.mu.RLock()
+	values := make([]MetricTensor, 0, st.tree.Size)
+	for v := range st.tree.All() {
+		values = append(values, v)
+	}
+	st.mu.RUnlock()
+	return slices.Values(values)
+}
+
+// AddFromChan works like BKTree.AddFromChan, the write lock is taken for each
+// value so that searches can run between insertions
+func (st *SyncBKTree) AddFromChan(ctx context.Context, ch <-chan MetricTensor) int {
+	return addFromChan(ctx, ch, func(val MetricTensor) bool {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		return st.tree.addCounted(val)
+	})
+}
+
+// AddFromChan adds the values received from ch until it is closed or ctx is done,
+// and returns how many of them were added (values already in the tree or rejected
+// by Add are skipped and not counted)
+func (tree *BKTree) AddFromChan(ctx context.Context, ch <-chan MetricTensor) int {
+	return addFromChan(ctx, ch, tree.addCounted)
+}
+
+func addFromChan(ctx context.Context, ch <-chan MetricTensor, add func(MetricTensor) bool) int {
+	added := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return added
+		case val, ok := <-ch:
+			if !ok {
+				return added
+			}
+			if add(val) {
+				added += 1
+			}
+		}
+	}
+}
+
+// addCounted adds val and reports whether the tree grew
+func (tree *BKTree) addCounted(val MetricTensor) bool {
+	size := tree.Size
+	return tree.Add(val) == nil && tree.Size > size
+}
@@ -0,0 +1,107 @@
+package go_bk_tree
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestBKTree_AddFromChan(t *testing.T) {
+	ch := make(chan MetricTensor)
+	go func() {
+		for _, w := range []string{"some", "soft", "some", "mole"} {
+			ch <- Word(w)
+		}
+		close(ch)
+	}()
+	tree := new(BKTree)
+	if added := tree.AddFromChan(context.Background(), ch); added != 3 {
+		t.Errorf("expected: %d, got: %d", 3, added)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if added := tree.AddFromChan(ctx, make(chan MetricTensor)); added != 0 {
+		t.Errorf("expected: %d, got: %d", 0, added)
+	}
+}
+
+func TestSyncBKTree_AddFromChan(t *testing.T) {
+	hashes, _ := makeRandomHammingTree(1000, 8)
+	ch := make(chan MetricTensor)
+	var tree SyncBKTree
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, h := range hashes {
+			tree.Search(h, 2)
+		}
+	}()
+	go func() {
+		for _, h := range hashes {
+			ch <- h
+		}
+		close(ch)
+	}()
+	if added := tree.AddFromChan(context.Background(), ch); added != len(hashes) {
+		t.Errorf("expected: %d, got: %d", len(hashes), added)
+	}
+	wg.Wait()
+	if tree.Size() != len(hashes) {
+		t.Errorf("expected: %d, got: %d", len(hashes), tree.Size())
+	}
+}
+
+func TestSyncBKTree_Snapshot(t *testing.T) {
+	var tree SyncBKTree
+	for _, w := range []string{"some", "soft", "same"} {
+		tree.Add(Word(w))
+	}
+	snapshot := tree.Snapshot()
+	tree.Add(Word("mole"))
+	count := 0
+	for v := range snapshot {
+		// adding while iterating over a snapshot must not deadlock
+		tree.Add(Word(v.ToString() + "s"))
+		count++
+	}
+	if count != 3 {
+		t.Errorf("expected: %d, got: %d", 3, count)
+	}
+	count = 0
+	for range tree.All() {
+		count++
+	}
+	if count != 7 || tree.Size() != 7 {
+		t.Errorf("expected: %d, got: %d (size %d)", 7, count, tree.Size())
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			tree.Add(Word(strconv.Itoa(i)))
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		for range tree.All() {
+		}
+	}
+	wg.Wait()
+}
+
+func TestSyncBKTree_Version(t *testing.T) {
+	var st SyncBKTree
+	st.Add(Word("some"))
+	_, _, v := st.SearchVersioned(Word("some"), 0)
+	if v != st.Version() {
+		t.Errorf("expected: %d, got: %d", st.Version(), v)
+	}
+	st.Add(Word("soft"))
+	if st.Version() == v {
+		t.Errorf("expected the results of version %d to be stale", v)
+	}
+}
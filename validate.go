@@ -0,0 +1,161 @@
+package go_bk_tree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalidTree is wrapped by the errors reporting a violation of the BK-tree invariant
+var ErrInvalidTree = errors.New("go_bk_tree: invalid tree")
+
+type ancestor struct {
+	node   *BkTreeNode
+	bucket Distance
+}
+
+// Validate checks the BK-tree invariant: every value stored under the bucket d of a node
+// is exactly d away from that node's value. It returns an error describing the first
+// violation found (buckets are visited in ascending order), nil if the tree is sound.
+func (tree *BKTree) Validate() error {
+	if tree.Root == nil {
+		return nil
+	}
+	return validateSubtree(tree.Root, nil)
+}
+
+// ValidateParallel runs the same checks as Validate, validating the subtrees of the
+// root's children concurrently with at most NumCPU goroutines. It returns all the
+// violations found joined in bucket order, one per invalid subtree of the root, so
+// that the first one reported is the error Validate returns.
+func (tree *BKTree) ValidateParallel() error {
+	if tree.Root == nil {
+		return nil
+	}
+	if tree.Root.MetricTensor == nil {
+		return validateSubtree(tree.Root, nil)
+	}
+	buckets := tree.Root.sortedBuckets()
+	errs := make([]error, len(buckets))
+	sem := make(chan struct{}, numCPU)
+	var wg sync.WaitGroup
+	for i, bucket := range buckets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, bucket Distance) {
+			defer wg.Done()
+			errs[i] = validateChild(tree.Root, bucket, nil)
+			<-sem
+		}(i, bucket)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func validateSubtree(node *BkTreeNode, path []ancestor) error {
+	if node.MetricTensor == nil {
+		return fmt.Errorf("%w: %w at %s", ErrInvalidTree, ErrNilValue, formatPath(path))
+	}
+	for _, a := range path {
+		if dist := a.node.DistanceFrom(node.MetricTensor); dist != a.bucket {
+			return fmt.Errorf("%w: %s at %s is %d away from %s, expected %d",
+				ErrInvalidTree, node.ToString(), formatPath(path), dist, a.node.ToString(), a.bucket)
+		}
+	}
+	for _, bucket := range node.sortedBuckets() {
+		if err := validateChild(node, bucket, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateChild(node *BkTreeNode, bucket Distance, path []ancestor) error {
+	path = append(path, ancestor{node, bucket})
+	child := node.Children[bucket]
+	if bucket <= 0 || child == nil {
+		return fmt.Errorf("%w: invalid child bucket at %s", ErrInvalidTree, formatPath(path))
+	}
+	return validateSubtree(child, path)
+}
+
+// formatPath formats the buckets followed from the root, e.g. "root/2/1"
+func formatPath(path []ancestor) string {
+	s := "root"
+	for _, a := range path {
+		s += fmt.Sprintf("/%d", a.bucket)
+	}
+	return s
+}
+
+// Repair restores the BK-tree invariant checked by Validate, e.g. for a tree loaded
+// from an index built with an older version of its metric, and returns the number of
+// misfiled nodes it found. Every node is checked against all its ancestors, which
+// costs a distance computation per ancestor of each node. A misfiled node is unlinked
+// with its whole subtree, whose values are then added back one by one as RemoveNode
+// does, so the mislocated parts of the tree are effectively rebuilt while the sound
+// ones are kept as they are. Nodes holding a nil value are dropped (their subtree
+// is added back too) and Size is recomputed.
+func (tree *BKTree) Repair() int {
+	if tree.Root == nil {
+		return 0
+	}
+	corrections := 0
+	var orphans []*BkTreeNode
+	detach := func(node *BkTreeNode) {
+		corrections++
+		for _, n := range collectNodes(node, nil) {
+			if n.MetricTensor != nil {
+				orphans = append(orphans, n)
+			}
+		}
+	}
+	// walk reports whether the subtree of node changed, which drops its frozen copy
+	var walk func(node *BkTreeNode, path []ancestor) bool
+	walk = func(node *BkTreeNode, path []ancestor) bool {
+		changed := false
+		for _, bucket := range node.sortedBuckets() {
+			child := node.Children[bucket]
+			childPath := append(path, ancestor{node, bucket})
+			if child != nil && child.MetricTensor != nil && fitsPath(child, childPath) {
+				if walk(child, childPath) {
+					changed = true
+				}
+				continue
+			}
+			delete(node.Children, bucket)
+			changed = true
+			if child != nil {
+				detach(child)
+			} else {
+				corrections++
+			}
+		}
+		if changed {
+			node.frozen = nil
+		}
+		return changed
+	}
+	if tree.Root.MetricTensor == nil {
+		detach(tree.Root)
+		tree.Root = nil
+	} else {
+		walk(tree.Root, nil)
+	}
+	tree.CalculateSize()
+	if corrections > 0 {
+		tree.version++
+	}
+	tree.reinsert(orphans)
+	return corrections
+}
+
+// fitsPath reports whether node is the bucket distance away from every ancestor of path
+func fitsPath(node *BkTreeNode, path []ancestor) bool {
+	for _, a := range path {
+		if a.node.DistanceFrom(node.MetricTensor) != a.bucket {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package go_bk_tree
+
+import "testing"
+
+func TestBKTree_Validate(t *testing.T) {
+	_, tree := makeRandomHammingTree(5000, 4)
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.ValidateParallel(); err != nil {
+		t.Fatal(err)
+	}
+	// corrupt two different subtrees of the root
+	var corrupted int
+	for _, bucket := range tree.Root.sortedBuckets() {
+		child := tree.Root.Children[bucket]
+		for _, b := range child.sortedBuckets() {
+			grandChild := child.Children[b]
+			grandChild.MetricTensor = grandChild.MetricTensor.(Hamming64) ^ 1
+			corrupted++
+			break
+		}
+		if corrupted == 2 {
+			break
+		}
+	}
+	err := tree.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	parallelErr := tree.ValidateParallel()
+	if parallelErr == nil {
+		t.Fatal("expected an error")
+	}
+	joined := parallelErr.(interface{ Unwrap() []error }).Unwrap()
+	if len(joined) != 2 {
+		t.Errorf("expected: %d, got: %d", 2, len(joined))
+	}
+	if joined[0].Error() != err.Error() {
+		t.Errorf("expected: %v, got: %v", err, joined[0])
+	}
+}
+
+func TestBKTree_Repair(t *testing.T) {
+	_, tree := makeRandomHammingTree(5000, 5)
+	if n := tree.Repair(); n != 0 {
+		t.Errorf("expected a sound tree to need no correction, got %d", n)
+	}
+	// corrupt children of the root, so that none is below another
+	corrupted := 0
+	for _, bucket := range tree.Root.sortedBuckets() {
+		if child := tree.Root.Children[bucket]; len(child.Children) > 0 && corrupted < 10 {
+			child.MetricTensor = child.MetricTensor.(Hamming64) ^ 1
+			corrupted++
+		}
+	}
+	var values []MetricTensor
+	for _, node := range collectNodes(tree.Root, nil) {
+		values = append(values, node.MetricTensor)
+	}
+	var leafParent *BkTreeNode
+	for _, child := range tree.Root.Children {
+		if len(child.Children) == 0 {
+			leafParent = child
+			break
+		}
+	}
+	leafParent.Children[3] = nil
+	size := tree.Size
+	if n := tree.Repair(); n != corrupted+1 {
+		t.Errorf("expected %d corrections, got %d", corrupted+1, n)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Size != size || tree.CountDistinct() != size {
+		t.Errorf("expected: %d, got: %d (%d counted)", size, tree.Size, tree.CountDistinct())
+	}
+	for _, v := range values {
+		if tree.Find(v) == nil {
+			t.Fatalf("expected %v to be kept", v)
+		}
+	}
+
+	tree.Root.MetricTensor = nil
+	if n := tree.Repair(); n != 1 || tree.Size != size-1 {
+		t.Errorf("expected the nil root to be dropped, got %d corrections and size %d", n, tree.Size)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
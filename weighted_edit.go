@@ -0,0 +1,60 @@
+package go_bk_tree
+
+import "math"
+
+// EditWeightScale is the factor weighted edit costs are multiplied by before being rounded to a Distance
+const EditWeightScale = 100
+
+// EditWeights configures the cost of each edit operation of WeightedWord
+type EditWeights struct {
+	Insert, Delete, Substitute float64
+	// Pairs overrides Substitute for specific (from, to) character pairs,
+	// e.g. {'0', 'O'}: 0.2 for visually similar characters
+	Pairs map[[2]rune]float64
+}
+
+// WeightedWord is a built-in MetricTensor computing a weighted Levenshtein distance, scaled
+// by EditWeightScale. The receiver's Weights are used, so every value of a tree should share
+// the same EditWeights.
+//
+// Notice: the result is only a metric (and Search only exact) when Insert == Delete, every
+// pair cost is symmetric ({a, b} and {b, a} cost the same) and no single substitution costs
+// more than deleting and inserting instead. Asymmetric weights violate the triangle
+// inequality and some matches may be missed.
+type WeightedWord struct {
+	Value   string
+	Weights *EditWeights
+}
+
+func (w WeightedWord) DistanceFrom(other MetricTensor) Distance {
+	s, t := []rune(w.Value), []rune(other.(WeightedWord).Value)
+	weights := w.Weights
+	prev := make([]float64, len(t)+1)
+	cur := make([]float64, len(t)+1)
+	for j := 1; j <= len(t); j++ {
+		prev[j] = prev[j-1] + weights.Insert
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = prev[0] + weights.Delete
+		for j := 1; j <= len(t); j++ {
+			sub := prev[j-1]
+			if s[i-1] != t[j-1] {
+				sub += weights.substitution(s[i-1], t[j-1])
+			}
+			cur[j] = math.Min(sub, math.Min(prev[j]+weights.Delete, cur[j-1]+weights.Insert))
+		}
+		prev, cur = cur, prev
+	}
+	return DistanceFromFloat(prev[len(t)] * EditWeightScale)
+}
+
+func (w WeightedWord) ToString() string {
+	return w.Value
+}
+
+func (weights *EditWeights) substitution(from, to rune) float64 {
+	if cost, ok := weights.Pairs[[2]rune{from, to}]; ok {
+		return cost
+	}
+	return weights.Substitute
+}
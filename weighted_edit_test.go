@@ -0,0 +1,34 @@
+package go_bk_tree
+
+import "testing"
+
+func TestWeightedWord_DistanceFrom(t *testing.T) {
+	weights := &EditWeights{
+		Insert: 1, Delete: 1, Substitute: 1,
+		Pairs: map[[2]rune]float64{{'0', 'o'}: 0.2, {'o', '0'}: 0.2},
+	}
+	word := func(s string) WeightedWord { return WeightedWord{s, weights} }
+	cases := []struct {
+		a, b     string
+		expected Distance
+	}{
+		{"foo", "foo", 0},
+		{"foo", "f0o", 20},
+		{"foo", "fao", 100},
+		{"foo", "fo", 100},
+		{"", "abc", 300},
+	}
+	for _, c := range cases {
+		if d := word(c.a).DistanceFrom(word(c.b)); d != c.expected {
+			t.Errorf("%s -> %s: expected: %d, got: %d", c.a, c.b, c.expected, d)
+		}
+	}
+	tree := new(BKTree)
+	for _, w := range []string{"foo", "bar", "fao", "f00"} {
+		tree.Add(word(w))
+	}
+	results, _ := tree.Search(word("foo"), 50)
+	if len(results) != 2 {
+		t.Errorf("expected: %d, got: %d (%v)", 2, len(results), results)
+	}
+}